@@ -26,7 +26,8 @@ func pushHandler(w http.ResponseWriter, r *http.Request) {
 	// parent request.
 	target := "/main.js"
 
-	if pusher, ok := w.(*muxie.Writer).ResponseWriter.(http.Pusher); ok {
+	// The handler's response writer is an http.Pusher when the underlying one is, i.e on HTTP/2.
+	if pusher, ok := w.(http.Pusher); ok {
 		err := pusher.Push(target, nil)
 		if err != nil {
 			if err == http.ErrNotSupported {
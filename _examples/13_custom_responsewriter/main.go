@@ -11,6 +11,10 @@ import (
 
 func main() {
 	mux := muxie.NewMux()
+	// Optionally, store the parameters to the request's context as well,
+	// so they can be retrieved through the `muxie.RequestParam(r, "name")`
+	// even if a third-party middleware replaces the response writer without an `Unwrap` method.
+	mux.ParamsMode = muxie.ParamsInContext
 	mux.Use(RequestTime)
 	mux.HandleFunc("/profile/:name", profileHandler)
 	fmt.Println(`Server started at http://localhost:8080
@@ -21,15 +25,14 @@ http://localhost:8080/profile/yourname`)
 }
 
 func profileHandler(w http.ResponseWriter, r *http.Request) {
-	name := muxie.GetParam(w, "name")
+	name := muxie.GetParam(w, "name") // OR muxie.RequestParam(r, "name")
 	fmt.Fprintf(w, "Hello, %s!", name)
 }
 
 type responseWriterWithTimer struct {
-	// muxie.ParamStore
-	// http.ResponseWriter
-	// OR
-	*muxie.Writer
+	http.ResponseWriter
+	// The `muxie.GetParam` walks through the `Unwrap` method (see below)
+	// to find the original `*muxie.Writer`, there is no need to embed it.
 	// OR/and implement the ParamStore interface by your own if you want
 	// to customize the way the parameters are stored and retrieved.
 	isHeaderWritten bool
@@ -40,11 +43,16 @@ type responseWriterWithTimer struct {
 // Look at: https://github.com/kataras/muxie/issues/10 too.
 func RequestTime(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// w.(muxie.ParamStore), w OR cast it directly to the *muxie.Writer:
-		next.ServeHTTP(&responseWriterWithTimer{w.(*muxie.Writer), false, time.Now()}, r)
+		next.ServeHTTP(&responseWriterWithTimer{w, false, time.Now()}, r)
 	})
 }
 
+// Unwrap returns the original response writer,
+// it is used by the `muxie.GetParam` and the `http.ResponseController`.
+func (w *responseWriterWithTimer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 func (w *responseWriterWithTimer) WriteHeader(statusCode int) {
 	elapsed := time.Since(w.start)
 	w.Header().Set("X-Response-Time", strconv.FormatInt(elapsed.Nanoseconds(), 10))
@@ -31,7 +31,9 @@ func main() {
 	mux := muxie.NewMux() // <-
 	mux.HandleFunc("/", serveHome)
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		serveWs(hub /* - > */, w.(*muxie.Writer).ResponseWriter /* <- */, r)
+		// The handler's response writer is an http.Hijacker when the underlying one is,
+		// so it can be passed to the websocket upgrader as it is.
+		serveWs(hub, w, r)
 	})
 
 	log.Printf("Open http://localhost%s/ in your browser.\n", *addr)
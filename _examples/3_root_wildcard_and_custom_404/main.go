@@ -12,6 +12,8 @@ func main() {
 	mux.PathCorrection = true
 
 	// matches everyhing if nothing else found, so you can use it for custom 404 main pages!
+	// If you don't need the closest wildcard resolution, use the `mux.NotFound(handler)` instead,
+	// it is executed through the `mux.Use` middlewares and it is inherited by the `mux.Of` sub muxes.
 	mux.HandleFunc("/*path", func(w http.ResponseWriter, r *http.Request) {
 		path := muxie.GetParam(w, "path")
 		fmt.Fprintf(w, "Site Custom 404 Error Message\nPage of: '%s' was unable to be found", path)
@@ -34,7 +34,8 @@ func main() {
 
 	// So far all good, nothing new shown above,
 	// let's see how we can get the registered endpoints based on a prefix or a root node,
-	// for more see the `muxie.Mux#Routes` godocs.
+	// for more see the `muxie.Mux#Routes` godocs
+	// and the `muxie.Mux#ListRoutes` and `muxie.Trie#Walk` below.
 	//
 	//
 	// request: http://localhost:8080/nodes/v1
@@ -56,6 +57,12 @@ func main() {
 		}
 	})
 
+	// Print the route table, the `muxie.Mux#ListRoutes` describes each registered route:
+	// its pattern, parameters and their kinds, tag, data, methods and middlewares.
+	for _, route := range mux.ListRoutes() {
+		fmt.Printf("%-16s params: %d methods: %v\n", route.Pattern, len(route.Params), route.Methods)
+	}
+
 	// http://localhost:8080
 	// http://localhost:8080/index
 	// http://localhost:8080/about
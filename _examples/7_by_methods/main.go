@@ -36,7 +36,13 @@ func main() {
 		})
 	*/
 
-	log.Println("Server started at http://localhost:8080\nGET: http://localhost:8080/users\nGET, POST, DELETE: http://localhost:8080/user/:id")
+	// Or register each method independently, the Mux answers
+	// with 405 and the "Allow" header, HEAD (by the GET handler) and OPTIONS automatically.
+	mux.Get("/users/:id", getUser)
+	mux.Post("/users/:id", saveUser)
+	mux.Delete("/users/:id", deleteUser)
+
+	log.Println("Server started at http://localhost:8080\nGET: http://localhost:8080/users\nGET, POST, DELETE: http://localhost:8080/user/:id\nGET, POST, DELETE: http://localhost:8080/users/:id")
 	log.Fatal(http.ListenAndServe(":8080", mux))
 }
 
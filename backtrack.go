@@ -0,0 +1,222 @@
+package muxie
+
+import "strings"
+
+// Backtracking makes the `Search` explore every child that a path segment matches, in order of priority,
+// and go back to the next one when a branch does not lead to a complete node, see `TrieOptions.Backtracking`.
+func (t *TrieOf[T]) Backtracking() *TrieOf[T] {
+	t.backtracking = true
+	return t
+}
+
+// backtracker holds the state of a backtracking search, see `Trie#searchBacktracking`.
+type backtracker[T any] struct {
+	// the original path and the one to search for, they differ on case-insensitive tries.
+	q, qc string
+	trace *ExplanationOf[T]
+	// the values of the parameters of the current branch.
+	values paramStack
+	// the specificity of the current branch and the number of its path segments, see `Match`.
+	specificity uint64
+	depth       int
+	// if true then the search goes on after a complete node is found and all of them are collected to the "matches".
+	collect bool
+	matches []MatchOf[T]
+}
+
+// paramStack is the stack of the parameter values of a backtracking search,
+// the values of a few parameters fit to a fixed array so the stack does not allocate.
+type paramStack struct {
+	fixed [8]string
+	n     int
+	more  []string
+}
+
+func (p *paramStack) push(value string) {
+	if p.n < len(p.fixed) {
+		p.fixed[p.n] = value
+	} else {
+		p.more = append(p.more[:p.n-len(p.fixed)], value)
+	}
+	p.n++
+}
+
+func (p *paramStack) get(i int) string {
+	if i < len(p.fixed) {
+		return p.fixed[i]
+	}
+
+	return p.more[i-len(p.fixed)]
+}
+
+// searchBacktracking searches the "q" by exploring the children of each path segment in order of priority,
+// the first complete node found is the one of the highest priority, see `TrieOptions.Backtracking`.
+// Like the `Search`, it does not allocate unless the path has more than 8 parameters
+// or upper case letters on a `CaseInsensitive` trie.
+func (t *TrieOf[T]) searchBacktracking(tree *trieTree[T], q string, params ParamsSetter, trace *ExplanationOf[T]) *NodeOf[T] {
+	b := backtracker[T]{q: q, qc: q, trace: trace}
+	if t.caseInsensitive {
+		b.qc = strings.ToLower(q)
+	}
+
+	n := b.match(tree.root, 1)
+	if n == nil {
+		trace.fallback(MatchNone, "")
+		return nil
+	}
+
+	for i := 0; i < b.values.n && i < len(n.paramKeys); i++ {
+		params.Set(n.paramKeys[i], b.values.get(i))
+	}
+
+	return n
+}
+
+// match matches the path segment which starts at "start", and the rest of the path, against the children of the "n".
+// It returns the complete node of the highest priority, the values of its parameters are pushed to the "values".
+func (b *backtracker[T]) match(n *NodeOf[T], start int) *NodeOf[T] {
+	i := len(b.q)
+	if idx := strings.IndexByte(b.q[start:], pathSepB); idx != -1 {
+		i = start + idx
+	}
+	s, sc := b.q[start:i], b.qc[start:i]
+	b.trace.segment(n, s, sc)
+
+	if child := n.getChild(sc); child != nil {
+		end, ok := i, true
+		if child.tail != "" {
+			end, _, ok = matchTail(b.qc, i, child.tail)
+		}
+
+		if ok {
+			if found := b.next(child, MatchStatic, b.q[start:end], end); found != nil {
+				return found
+			}
+		}
+	}
+
+	if n.childPrefixParameter {
+		for _, l := range n.childPrefixLengths {
+			if l > len(sc) {
+				continue
+			}
+
+			if child := n.getChild(sc[:l] + PrefixParamStart); child != nil {
+				if found := b.nextParam(child, MatchPrefixParam, s, i); found != nil {
+					return found
+				}
+			}
+		}
+	}
+
+	if n.childSuffixParameter {
+		for _, l := range n.childSuffixLengths {
+			if l > len(sc) {
+				continue
+			}
+
+			if child := n.getChild(SuffixParamStart + sc[len(sc)-l:]); child != nil {
+				if found := b.nextParam(child, MatchSuffixParam, s, i); found != nil {
+					return found
+				}
+			}
+		}
+	}
+
+	for _, key := range n.childMixedParameters {
+		child := n.getChild(key)
+		var buf [8]string
+		if mixedValues, ok := child.mixed.match(s, sc, buf[:0]); ok {
+			top := b.values.n
+			for _, v := range mixedValues {
+				b.values.push(v)
+			}
+
+			if found := b.next(child, MatchMixedParam, s, i); found != nil {
+				return found
+			}
+			b.values.n = top
+		}
+	}
+
+	for _, key := range n.childConstrainedParameters {
+		if child := n.getChild(key); child.constraint(s) {
+			if found := b.nextParam(child, MatchConstrainedParam, s, i); found != nil {
+				return found
+			}
+		}
+	}
+
+	if n.childNamedParameter {
+		if found := b.nextParam(n.getChild(ParamStart), MatchNamedParam, s, i); found != nil {
+			return found
+		}
+	}
+
+	if n.childWildcardParameter {
+		// the wildcard takes the rest of the path.
+		child := n.getChild(WildcardParamStart)
+		b.trace.attempt(MatchWildcard, child, b.q[start:])
+		if child.end {
+			b.values.push(b.q[start:])
+			if b.found(child) {
+				return child
+			}
+			b.values.n--
+		}
+	}
+
+	b.trace.attempt(MatchNone, nil, s)
+	return nil
+}
+
+// nextParam is like the `next` but the "child" is a single parameter, its value is the path segment "s".
+func (b *backtracker[T]) nextParam(child *NodeOf[T], rule MatchRule, s string, i int) *NodeOf[T] {
+	top := b.values.n
+	b.values.push(s)
+	if found := b.next(child, rule, s, i); found != nil {
+		return found
+	}
+
+	b.values.n = top
+	return nil
+}
+
+// next continues the search after the "child" matched the path segment "s", which ends at "i", by the "rule".
+func (b *backtracker[T]) next(child *NodeOf[T], rule MatchRule, s string, i int) *NodeOf[T] {
+	b.trace.attempt(rule, child, s)
+	specificity, depth := b.specificity, b.depth
+	// a compressed node takes more than one static path segment.
+	for levels := child.levels(); levels > 0; levels-- {
+		b.specificity += segmentSpecificity(rule, b.depth)
+		b.depth++
+	}
+
+	if i == len(b.q) {
+		if child.end && b.found(child) {
+			return child
+		}
+	} else if found := b.match(child, i+1); found != nil {
+		return found
+	}
+
+	b.specificity, b.depth = specificity, depth
+	b.trace.fallback(MatchBacktrack, s)
+	return nil
+}
+
+// found reports whether the search should stop at the complete node "n",
+// when the search collects all the complete nodes then it adds a `Match` for the "n" and goes on.
+func (b *backtracker[T]) found(n *NodeOf[T]) bool {
+	if !b.collect {
+		return true
+	}
+
+	m := MatchOf[T]{Node: n, Specificity: b.specificity}
+	for i := 0; i < b.values.n && i < len(n.paramKeys); i++ {
+		m.Params = append(m.Params, ParamEntry{Key: n.paramKeys[i], Value: b.values.get(i)})
+	}
+	b.matches = append(b.matches, m)
+
+	return false
+}
@@ -0,0 +1,85 @@
+package muxie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrieBacktracking(t *testing.T) {
+	tree := NewTrie().Backtracking()
+	tree.Insert("/a/b/c/z", WithTag("static"))
+	tree.Insert("/a/:p1/c/d", WithTag("p1"))
+	tree.Insert("/x/:a/b/c/z", WithTag("a"))
+	tree.Insert("/x/:a/:b/c/d", WithTag("a_b"))
+	tree.Insert("/x/:a/:b/*rest", WithTag("a_b_rest"))
+	tree.Insert("/files/img+:name/raw", WithTag("img_raw"))
+	tree.Insert("/files/im+:name/meta", WithTag("im_meta"))
+	tree.Insert("/files/:name.:ext/meta", WithTag("mixed_meta"))
+	tree.Insert("/files/:id<int>/meta", WithTag("int_meta"))
+	tree.Insert("/files/:name/meta/:field", WithTag("named_field"))
+	tree.Insert("/files/*path", WithTag("files_wildcard"))
+	tree.Insert("/*any", WithTag("root_wildcard"))
+
+	tests := []struct {
+		path   string
+		tag    string
+		params []ParamEntry
+	}{
+		{"/a/b/c/d", "p1", []ParamEntry{{"p1", "b"}}},
+		{"/a/b/c/z", "static", nil},
+		{"/x/1/b/c/d", "a_b", []ParamEntry{{"a", "1"}, {"b", "b"}}},
+		{"/x/1/b/c/z", "a", []ParamEntry{{"a", "1"}}},
+		// the named parameters of the third segment win over the wildcard.
+		{"/x/1/b/c/y", "a_b_rest", []ParamEntry{{"a", "1"}, {"b", "b"}, {"rest", "c/y"}}},
+		// the longest prefix first, then the shorter one.
+		{"/files/imgx/raw", "img_raw", []ParamEntry{{"name", "imgx"}}},
+		{"/files/imgx/meta", "im_meta", []ParamEntry{{"name", "imgx"}}},
+		{"/files/image/raw", "files_wildcard", []ParamEntry{{"path", "image/raw"}}},
+		// then mixed, constrained and named parameters.
+		{"/files/a.txt/meta", "mixed_meta", []ParamEntry{{"name", "a"}, {"ext", "txt"}}},
+		{"/files/42/meta", "int_meta", []ParamEntry{{"id", "42"}}},
+		{"/files/42/meta/size", "named_field", []ParamEntry{{"name", "42"}, {"field", "size"}}},
+		// and the wildcards last.
+		{"/files/42/other", "files_wildcard", []ParamEntry{{"path", "42/other"}}},
+		{"/other", "root_wildcard", []ParamEntry{{"any", "other"}}},
+	}
+
+	for _, tt := range tests {
+		params := new(Writer)
+		n := tree.Search(tt.path, params)
+		if n == nil || n.Tag != tt.tag {
+			t.Fatalf("%s: expected to be found by: %s but got: %v", tt.path, tt.tag, n)
+		}
+
+		if got := params.GetAll(); !reflect.DeepEqual(tt.params, got) && (len(tt.params) != 0 || len(got) != 0) {
+			t.Fatalf("%s: expected params: %v but got: %v", tt.path, tt.params, got)
+		}
+	}
+}
+
+func TestTrieBacktrackingNotFound(t *testing.T) {
+	tree := NewTrieWithOptions(TrieOptions{Backtracking: true, CaseInsensitive: true, Compress: true})
+	tree.Insert("/api/v1/users/:id/profile", WithTag("profile"))
+	tree.Insert("/api/:version/users/list", WithTag("list"))
+
+	if n := tree.Search("/API/V1/Users/LIST", new(Writer)); n == nil || n.Tag != "list" {
+		t.Fatalf("expected the route with the parameter to be found after the compressed static one but got: %v", n)
+	}
+
+	if n := tree.Search("/api/v1/users/42", new(Writer)); n != nil {
+		t.Fatalf("expected not found but got: %s", n.String())
+	}
+
+	e := tree.Explain("/api/v1/users/list")
+	if e.Pattern != "/api/:version/users/list" {
+		t.Fatalf("expected the explanation to find the route with the parameter but got: %s", e.String())
+	}
+
+	var backtracked bool
+	for _, step := range e.Steps {
+		backtracked = backtracked || step.Rule == MatchBacktrack
+	}
+	if !backtracked {
+		t.Fatalf("expected a backtrack step but got:\n%s", e.String())
+	}
+}
@@ -0,0 +1,199 @@
+package muxie
+
+import "strings"
+
+// Compress merges the chains of static path segments, where each node has a single child,
+// to their last node, i.e the "/api/v1/org/settings" is stored to a single node instead of four.
+// The trie takes less memory and the search goes through fewer nodes.
+// The routes already inserted are compressed too.
+//
+// The nodes of a compressed trie are not one per path segment, so the `Node#Parent` of a node
+// may skip path segments. The `Search` results are the same.
+func (t *TrieOf[T]) Compress() *TrieOf[T] {
+	t.write(func(tree *trieTree[T]) {
+		t.compress = true
+		compactAll(tree.root, "")
+	})
+
+	return t
+}
+
+// isStaticKey reports whether the "key" of a child node is a static path segment.
+func isStaticKey(key string) bool {
+	return key != "" && key[0] != ParamStart[0] && key[0] != WildcardParamStart[0] &&
+		!isPrefixParam(key) && !isSuffixParam(key)
+}
+
+// levels returns the number of the path segments that this node takes, more than one if it is compressed.
+func (n *NodeOf[T]) levels() int {
+	if n.tail == "" {
+		return 1
+	}
+
+	return strings.Count(n.tail, pathSep) + 2
+}
+
+// canMerge reports whether this node may be replaced by its single child, see `mergeChild`.
+func (n *NodeOf[T]) canMerge() bool {
+	return n.parent != nil && !n.end && n.children.len() == 1
+}
+
+// mergeChild replaces this node, which is stored under the "key" of its parent, with its single child,
+// the child takes this node's path segment in front of its tail. It returns the child or nil if this node can not be merged:
+// it is the root, it is a complete node, it has more children or its or its child's path segment is not static.
+func (n *NodeOf[T]) mergeChild(key string) *NodeOf[T] {
+	if !n.canMerge() || !isStaticKey(key) {
+		return nil
+	}
+
+	var (
+		childKey string
+		child    *NodeOf[T]
+	)
+	n.children.each(func(s string, c *NodeOf[T]) {
+		childKey, child = s, c
+	})
+	if !isStaticKey(childKey) {
+		return nil
+	}
+
+	tail := childKey
+	if child.tail != "" {
+		tail += pathSep + child.tail
+	}
+	if n.tail != "" {
+		tail = n.tail + pathSep + tail
+	}
+
+	child.tail = tail
+	child.parent = n.parent
+	n.parent.children.replace(key, child)
+	n.parent = nil
+	return child
+}
+
+// split is the opposite of `mergeChild`, it moves the first path segment of this compressed node,
+// which is stored under the "key" of its parent, to a new node which takes its place and it returns the new node.
+// The "maxSorted" is the children layout of the new node, see `nodeChildren`.
+func (n *NodeOf[T]) split(key string, maxSorted int) *NodeOf[T] {
+	parent := n.parent
+	head := new(NodeOf[T])
+	head.pathIndex = parent.pathIndex + 1
+	head.paramCount = parent.paramCount
+	parent.children.replace(key, head)
+	head.parent = parent
+
+	childKey := n.tail
+	if i := strings.IndexByte(n.tail, pathSepB); i != -1 {
+		childKey, n.tail = n.tail[:i], n.tail[i+1:]
+	} else {
+		n.tail = ""
+	}
+	head.addChild(childKey, n, maxSorted)
+
+	return head
+}
+
+// compact merges the nodes from the "n" up to the root that are left with a single child.
+func compact[T any](n *NodeOf[T]) {
+	for ; n != nil && n.parent != nil; n = n.parent {
+		if !n.canMerge() {
+			continue
+		}
+
+		key, _ := n.parent.children.keyOf(n)
+		if child := n.mergeChild(key); child != nil {
+			n = child
+		}
+	}
+}
+
+// compactPath is like `compact` but the "keys" of the nodes from the root to the "n" are known,
+// the last one is the "n"'s key.
+func compactPath[T any](n *NodeOf[T], keys []string) {
+	for i := len(keys) - 1; i >= 0; i-- {
+		if child := n.mergeChild(keys[i]); child != nil {
+			n = child
+		}
+		n = n.parent
+	}
+}
+
+// compactAll merges the "n", which is stored under the "key" of its parent, and its children, the children first.
+func compactAll[T any](n *NodeOf[T], key string) {
+	n.children.each(func(s string, child *NodeOf[T]) {
+		compactAll(child, s)
+	})
+
+	n.mergeChild(key)
+}
+
+// matchTail matches the tail of a compressed node against the path segments after the "i" index of the "path".
+// It returns the end of the matched path segments and the number of them,
+// "ok" is true if all the tail's segments are matched.
+func matchTail(path string, i int, tail string) (end, matched int, ok bool) {
+	for {
+		if i == len(path) {
+			return i, matched, false
+		}
+
+		s := tail
+		next := strings.IndexByte(tail, pathSepB)
+		if next != -1 {
+			s = tail[:next]
+		}
+
+		j := i + 1 + len(s)
+		if j > len(path) || path[i+1:j] != s || (j < len(path) && path[j] != pathSepB) {
+			return i, matched, false
+		}
+
+		i = j
+		matched++
+		if next == -1 {
+			return i, matched, true
+		}
+		tail = tail[next+1:]
+	}
+}
+
+// matchTailSegments returns the number of the path pattern "segments" that match the tail of the compressed node "n".
+func (t *TrieOf[T]) matchTailSegments(n *NodeOf[T], segments []string) (matched int) {
+	tail := n.tail
+	for _, s := range segments {
+		next := strings.IndexByte(tail, pathSepB)
+		if next == -1 {
+			if t.segmentKey(s) == tail {
+				matched++
+			}
+			return
+		}
+
+		if t.segmentKey(s) != tail[:next] {
+			return
+		}
+		matched++
+		tail = tail[next+1:]
+	}
+
+	return
+}
+
+// lookup returns the node of the path pattern "segments", nil if it is not registered.
+func (t *TrieOf[T]) lookup(n *NodeOf[T], segments []string) *NodeOf[T] {
+	for i := 0; i < len(segments); i++ {
+		if n = n.getChild(t.segmentKey(segments[i])); n == nil {
+			return nil
+		}
+
+		if n.tail != "" {
+			levels := n.levels() - 1
+			if t.matchTailSegments(n, segments[i+1:]) != levels {
+				return nil
+			}
+			i += levels
+		}
+	}
+
+	return n
+}
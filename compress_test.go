@@ -0,0 +1,159 @@
+package muxie
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var compressPatterns = []string{
+	"/",
+	"/api/v1/org/settings/billing",
+	"/api/v1/org/settings/profile",
+	"/api/v1/org/:id/members",
+	"/api/v1/org/:id/members/:member/roles/list",
+	"/api/v2/status",
+	"/static/assets/css/*file",
+	"/static/assets/css/main/theme.css",
+	"/files/img+:name",
+	"/files/name-:.txt",
+	"/a/b/c/z",
+	"/a/:p1/c/d",
+	"/x/:a/b/c/z",
+	"/x/:a/:b/c/d",
+	"/users/:id<int>/profile/settings",
+	"/users/:name/profile/settings/privacy",
+	"/second/wild/*p",
+	"/second/wild/static/otherstatic",
+	"/hello/*p",
+	"/hello/:p1/static/:p2",
+}
+
+// compressPaths returns the paths of the "patterns" with their parameters filled,
+// their parents and some paths that do not match them.
+func compressPaths(patterns []string) (paths []string) {
+	for _, pattern := range patterns {
+		segments := slowPathSplit(pattern)
+		for i, s := range segments {
+			if isParamSegment(s) {
+				segments[i] = "42"
+			}
+		}
+
+		path := "/" + strings.Join(segments, "/")
+		paths = append(paths, path, path+"/", path+"/more", strings.ToUpper(path))
+		for i := range segments {
+			parent := "/" + strings.Join(segments[:i], "/")
+			paths = append(paths, parent, parent+"/other", parent+"/other/static")
+		}
+	}
+
+	return
+}
+
+func TestTrieCompress(t *testing.T) {
+	for _, options := range []TrieOptions{
+		{},
+		{SearchUnvisitedParams: true},
+		{CaseInsensitive: true},
+		{CaseInsensitive: true, SearchUnvisitedParams: true},
+	} {
+		options := options
+		t.Run(fmt.Sprintf("%+v", options), func(t *testing.T) {
+			tree := NewTrieWithOptions(options)
+			compressedOptions := options
+			compressedOptions.Compress = true
+			compressed := NewTrieWithOptions(compressedOptions)
+			for _, pattern := range compressPatterns {
+				tree.Insert(pattern, WithTag(pattern))
+				compressed.Insert(pattern, WithTag(pattern))
+			}
+
+			expectSameSearch(t, tree, compressed, compressPaths(compressPatterns))
+
+			// delete half of the routes, the chains are merged again.
+			for i, pattern := range compressPatterns {
+				if i%2 == 0 {
+					tree.Delete(pattern)
+					compressed.Delete(pattern)
+				}
+			}
+
+			expectSameSearch(t, tree, compressed, compressPaths(compressPatterns))
+		})
+	}
+}
+
+func expectSameSearch(t *testing.T, tree, compressed *Trie, paths []string) {
+	t.Helper()
+
+	for _, path := range paths {
+		params, compressedParams := new(Writer), new(Writer)
+		expected, got := tree.Search(path, params), compressed.Search(path, compressedParams)
+		if nodeKey(expected) != nodeKey(got) {
+			t.Fatalf("%s: expected to be found by: %q but got: %q", path, nodeKey(expected), nodeKey(got))
+		}
+
+		if !reflect.DeepEqual(params.GetAll(), compressedParams.GetAll()) {
+			t.Fatalf("%s: expected params: %v but got: %v", path, params.GetAll(), compressedParams.GetAll())
+		}
+	}
+}
+
+func nodeKey(n *Node) string {
+	if n == nil {
+		return "<nil>"
+	}
+
+	return n.key
+}
+
+func TestTrieCompressNodes(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/api/v1/org/settings/billing", WithTag("billing"))
+	tree.Compress()
+
+	n := tree.Search("/api/v1/org/settings/billing", new(Writer))
+	if n == nil || n.Tag != "billing" {
+		t.Fatalf("expected the route to be found")
+	}
+	if n.Parent() != tree.load().root {
+		t.Fatalf("expected the static path segments to be merged to a single node")
+	}
+
+	// split in the middle.
+	tree.Insert("/api/v1/org", WithTag("org"))
+	tree.Insert("/api/v1/status", WithTag("status"))
+	expectKeys := func(n *Node, keys ...string) {
+		t.Helper()
+		if got := n.childKeys(); !reflect.DeepEqual(keys, got) {
+			t.Fatalf("expected child keys: %v but got: %v", keys, got)
+		}
+	}
+
+	root := tree.load().root
+	expectKeys(root, "api")
+	api := root.getChild("api")
+	if api.tail != "v1" {
+		t.Fatalf("expected the tail of the /api/v1 node to be: v1 but got: %s", api.tail)
+	}
+	expectKeys(api, "org", "status")
+	if org := api.getChild("org"); !org.end || org.Tag != "org" || org.getChild("settings").tail != "billing" {
+		t.Fatalf("expected the /api/v1/org node to keep the settings/billing chain")
+	}
+
+	if n := tree.SearchPrefix("/api/v1/org/settings"); n == nil || n.Tag != "billing" {
+		t.Fatalf("expected the prefix to be found inside the compressed node")
+	}
+	if tree.HasPrefix("/api/v1/org/settings/other") {
+		t.Fatalf("expected the prefix to not be found")
+	}
+
+	tree.Delete("/api/v1/org")
+	tree.Delete("/api/v1/status")
+	// the trie is searched, so the writes work on a copy of the nodes.
+	if n := tree.load().root.getChild("api"); n.tail != "v1/org/settings/billing" || n.Tag != "billing" {
+		t.Fatalf("expected the nodes to be merged again after delete")
+	}
+}
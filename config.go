@@ -0,0 +1,295 @@
+package muxie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Registry holds the handlers and the middlewares that a route configuration refers to by name,
+// see `LoadMux`.
+type Registry struct {
+	handlers    map[string]http.Handler
+	middlewares map[string]Wrapper
+}
+
+// NewRegistry returns a new, empty, Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers:    make(map[string]http.Handler),
+		middlewares: make(map[string]Wrapper),
+	}
+}
+
+// Handle registers a handler by its "name", i.e:
+// registry.Handle("maintenance", maintenanceHandler)
+func (r *Registry) Handle(name string, handler http.Handler) *Registry {
+	if handler == nil {
+		panic("muxie/Registry#Handle: " + name + ": empty handler")
+	}
+
+	r.handlers[name] = handler
+	return r
+}
+
+// HandleFunc registers a handler function by its "name".
+func (r *Registry) HandleFunc(name string, handlerFunc func(http.ResponseWriter, *http.Request)) *Registry {
+	return r.Handle(name, http.HandlerFunc(handlerFunc))
+}
+
+// Middleware registers a middleware by its "name", i.e:
+// registry.Middleware("auth", authMiddleware)
+func (r *Registry) Middleware(name string, middleware Wrapper) *Registry {
+	if middleware == nil {
+		panic("muxie/Registry#Middleware: " + name + ": empty middleware")
+	}
+
+	r.middlewares[name] = middleware
+	return r
+}
+
+// RoutesConfig is a group of routes of a route configuration, the document itself is the root group.
+// A group registers its routes and its groups to a sub mux of its "prefix", like the `Mux#Of`.
+type RoutesConfig struct {
+	// Prefix is the path prefix of the group, relative to its parent group.
+	Prefix string `json:"prefix,omitempty"`
+	// Middlewares are the names of the middlewares of the group's routes and groups, see `Mux#Use`.
+	Middlewares []string `json:"middlewares,omitempty"`
+	// NotFound is the name of the not found handler of the group, see `Mux#NotFound`.
+	NotFound string `json:"notFound,omitempty"`
+	// Routes are the routes of the group, in order of registration.
+	Routes []RouteConfig `json:"routes,omitempty"`
+	// Groups are the sub groups of the group.
+	Groups []RoutesConfig `json:"groups,omitempty"`
+}
+
+// RouteConfig is a route of a route configuration.
+type RouteConfig struct {
+	// Pattern is the path pattern of the route, relative to its group's prefix.
+	Pattern string `json:"pattern"`
+	// Methods are the HTTP methods of the route, any method if empty, see `Mux#HandleMethod`.
+	Methods []string `json:"methods,omitempty"`
+	// Name is the name of the route for the `Mux#URL`, see `WithTag`.
+	Name string `json:"name,omitempty"`
+	// Handler is the name of the route's handler.
+	Handler string `json:"handler"`
+	// Middlewares are the names of the middlewares of the route, they run after the ones of its groups.
+	Middlewares []string `json:"middlewares,omitempty"`
+	// Host is the host pattern of the route, i.e "admin.app.com", "{tenant}.app.com" or "*.app.com",
+	// the route is registered to the route table of the host pattern, see `Mux#Handle`.
+	Host string `json:"host,omitempty"`
+	// Headers are the `Header` matchers of the route, a request should pass all of them.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// ParseRoutesConfig parses a JSON or a YAML route configuration, i.e:
+//
+//	prefix: /api
+//	middlewares: [logger]
+//	routes:
+//	  - pattern: /users/:id
+//	    methods: [GET]
+//	    name: user
+//	    handler: getUser
+//	  - pattern: /admin/*path
+//	    handler: admin
+//	    host: "{tenant}.app.com"
+//	    headers:
+//	      X-Admin: "true"
+//	groups:
+//	  - prefix: /v2
+//	    middlewares: [auth]
+//	    routes:
+//	      - pattern: /users/:id
+//	        handler: maintenance
+//
+// A document which starts with a "{" is JSON, otherwise YAML. The unknown keys are errors.
+//
+// The YAML is read without an external dependency, it supports the subset that a route configuration needs:
+// block mappings and sequences indented by spaces, plain, single and double-quoted scalars,
+// flow sequences and mappings on a single line, i.e "[GET, POST]", comments and a single document.
+// Block scalars ("|" and ">"), multi-line plain or quoted scalars, anchors and aliases ("&" and "*"), tags ("!"),
+// complex mapping keys ("?"), duplicate keys, tabs for indentation and multiple documents are errors.
+func ParseRoutesConfig(data []byte) (*RoutesConfig, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		var err error
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("muxie: route configuration: %w", err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	config := new(RoutesConfig)
+	if err := dec.Decode(config); err != nil {
+		return nil, fmt.Errorf("muxie: route configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// LoadMux returns a new Mux with the routes of the JSON or YAML route configuration,
+// its handlers and middlewares are resolved by name from the "registry". See `ParseRoutesConfig`.
+//
+// The routing can be changed without a recompile by loading the configuration file again
+// and serving the new Mux, i.e on a change of the file, the current Mux keeps serving if the new configuration fails to load.
+func LoadMux(data []byte, registry *Registry) (*Mux, error) {
+	config, err := ParseRoutesConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.NewMux(registry)
+}
+
+// NewMux returns a new Mux with the routes of this configuration, see `LoadMux`.
+func (c *RoutesConfig) NewMux(registry *Registry) (mux *Mux, err error) {
+	if err = c.validate(registry, ""); err != nil {
+		return nil, err
+	}
+
+	// the routes are validated, the patterns may still be invalid.
+	defer func() {
+		if v := recover(); v != nil {
+			mux, err = nil, fmt.Errorf("muxie: route configuration: %v", v)
+		}
+	}()
+
+	mux = NewMux()
+	c.register(mux, registry)
+	return mux, nil
+}
+
+// validate reports the first handler or middleware that the "registry" does not have,
+// "prefix" is the absolute prefix of the group.
+func (c *RoutesConfig) validate(registry *Registry, prefix string) error {
+	prefix += c.Prefix
+
+	if c.NotFound != "" {
+		if _, ok := registry.handlers[c.NotFound]; !ok {
+			return fmt.Errorf("muxie: route configuration: group %q: unknown not found handler %q", prefix, c.NotFound)
+		}
+	}
+
+	for _, name := range c.Middlewares {
+		if _, ok := registry.middlewares[name]; !ok {
+			return fmt.Errorf("muxie: route configuration: group %q: unknown middleware %q", prefix, name)
+		}
+	}
+
+	for _, route := range c.Routes {
+		pattern := prefix + route.Pattern
+		if route.Pattern == "" {
+			return fmt.Errorf("muxie: route configuration: group %q: empty pattern", prefix)
+		}
+
+		if route.Host == "" {
+			if _, _, err := splitHostPattern(route.Pattern); err != nil {
+				return fmt.Errorf("muxie: route configuration: route %q: %w", pattern, err)
+			}
+		} else {
+			if route.Pattern[0] != pathSepB {
+				return fmt.Errorf("muxie: route configuration: route %q: the pattern of a host should start with a slash", pattern)
+			}
+			if _, _, _, err := parseHostPattern(route.Host); err != nil || strings.ContainsRune(route.Host, pathSepB) {
+				return fmt.Errorf("muxie: route configuration: route %q: invalid host pattern %q", pattern, route.Host)
+			}
+		}
+
+		if _, ok := registry.handlers[route.Handler]; !ok {
+			return fmt.Errorf("muxie: route configuration: route %q: unknown handler %q", pattern, route.Handler)
+		}
+
+		for _, name := range route.Middlewares {
+			if _, ok := registry.middlewares[name]; !ok {
+				return fmt.Errorf("muxie: route configuration: route %q: unknown middleware %q", pattern, name)
+			}
+		}
+	}
+
+	for i := range c.Groups {
+		if err := c.Groups[i].validate(registry, prefix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *RoutesConfig) register(mux SubMux, registry *Registry) {
+	mux = mux.Of(c.Prefix)
+
+	for _, name := range c.Middlewares {
+		mux.Use(registry.middlewares[name])
+	}
+
+	if c.NotFound != "" {
+		mux.NotFound(registry.handlers[c.NotFound])
+	}
+
+	for _, route := range c.Routes {
+		route.register(mux, registry)
+	}
+
+	for i := range c.Groups {
+		c.Groups[i].register(mux, registry)
+	}
+}
+
+func (route RouteConfig) register(mux SubMux, registry *Registry) {
+	var middlewares Wrappers
+	for _, name := range route.Middlewares {
+		middlewares = append(middlewares, registry.middlewares[name])
+	}
+	handler := middlewares.For(registry.handlers[route.Handler])
+
+	var options []InsertOption
+	if route.Name != "" {
+		options = append(options, WithTag(route.Name))
+	}
+
+	// i.e "{tenant}.app.com/projects/:id", a single label host is written with a trailing dot.
+	pattern := route.Pattern
+	if host := route.Host; host != "" {
+		if !isExplicitHost(host) {
+			host += "."
+		}
+		pattern = host + pattern
+	}
+
+	if matchers := route.matchers(); len(matchers) > 0 {
+		// the method is a condition too, the requests of other methods are not acceptable instead of not allowed.
+		if len(route.Methods) > 0 {
+			matchers = append([]Matcher{Method(route.Methods...)}, matchers...)
+		}
+
+		mux.HandleWhen(pattern, And(matchers...), handler, options...)
+		return
+	}
+
+	if len(route.Methods) > 0 {
+		mux.HandleMethod(strings.Join(route.Methods, ", "), pattern, handler, options...)
+		return
+	}
+
+	mux.Handle(pattern, handler, options...)
+}
+
+// matchers returns the matchers of the route's headers, sorted by name.
+func (route RouteConfig) matchers() (matchers []Matcher) {
+	names := make([]string, 0, len(route.Headers))
+	for name := range route.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		matchers = append(matchers, Header(name, route.Headers[name]))
+	}
+
+	return
+}
@@ -0,0 +1,233 @@
+package muxie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testRoutesConfigYAML = `# the routes of the api.
+prefix: /api
+middlewares: [trace]
+notFound: notFound
+routes:
+  - pattern: /users/:id
+    methods: [GET, PUT]
+    name: user
+    handler: user
+  - pattern: "/admin/*path"
+    handler: admin
+    host: admin.app.com
+    headers:
+      X-Admin: 'true'
+  - pattern: /home
+    handler: tenant
+    host: "{tenant}.app.com"
+  - pattern: /status
+    handler: status # any method.
+    middlewares:
+    - cache
+groups:
+  - prefix: /v2
+    middlewares: [auth]
+    routes:
+      - {pattern: /users/:id, handler: maintenance}
+`
+
+const testRoutesConfigJSON = `{
+	"prefix": "/api",
+	"middlewares": ["trace"],
+	"notFound": "notFound",
+	"routes": [
+		{"pattern": "/users/:id", "methods": ["GET", "PUT"], "name": "user", "handler": "user"},
+		{"pattern": "/admin/*path", "handler": "admin", "host": "admin.app.com", "headers": {"X-Admin": "true"}},
+		{"pattern": "/home", "handler": "tenant", "host": "{tenant}.app.com"},
+		{"pattern": "/status", "handler": "status", "middlewares": ["cache"]}
+	],
+	"groups": [
+		{"prefix": "/v2", "middlewares": ["auth"], "routes": [{"pattern": "/users/:id", "handler": "maintenance"}]}
+	]
+}`
+
+func testRegistry() *Registry {
+	text := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s + GetParam(w, "id")))
+		}
+	}
+
+	header := func(key, value string) Wrapper {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add(key, value)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	return NewRegistry().
+		Handle("user", text("user")).
+		Handle("admin", text("admin")).
+		Handle("status", text("ok")).
+		HandleFunc("maintenance", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+		}).
+		Handle("notFound", text("not found")).
+		HandleFunc("tenant", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("tenant " + GetParam(w, "tenant")))
+		}).
+		Middleware("trace", header("X-Trace", "1")).
+		Middleware("cache", header("Cache-Control", "no-cache")).
+		Middleware("auth", header("X-Auth", "1"))
+}
+
+func TestLoadMux(t *testing.T) {
+	for format, data := range map[string]string{"yaml": testRoutesConfigYAML, "json": testRoutesConfigJSON} {
+		mux, err := LoadMux([]byte(data), testRegistry())
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		testHandler(t, mux, http.MethodGet, "/api/users/42").statusCode(http.StatusOK).bodyEq("user42").headerEq("X-Trace", "1")
+		testHandler(t, mux, http.MethodPut, "/api/users/42").statusCode(http.StatusOK).bodyEq("user42")
+		testHandler(t, mux, http.MethodDelete, "/api/users/42").statusCode(http.StatusMethodNotAllowed)
+		testHandler(t, mux, http.MethodPost, "/api/status").statusCode(http.StatusOK).bodyEq("ok").headerEq("Cache-Control", "no-cache")
+		testHandler(t, mux, http.MethodGet, "/api/v2/users/42").statusCode(http.StatusServiceUnavailable).headerEq("X-Auth", "1")
+		testHandler(t, mux, http.MethodGet, "/api/other").statusCode(http.StatusOK).bodyEq("not found")
+
+		// the admin route needs both the host and the header.
+		for _, tt := range []struct {
+			host, header, expected string
+		}{
+			{"app.com", "true", "not found"},
+			{"admin.app.com", "", "Not Acceptable\n"},
+			{"admin.app.com", "true", "admin"},
+			// the host patterns are matched too.
+			{"acme.app.com", "", "not found"},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "http://"+tt.host+"/api/admin/settings", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Admin", tt.header)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Body.String() != tt.expected {
+				t.Fatalf("%s: %s: expected: %q but got: %q", format, tt.host, tt.expected, w.Body.String())
+			}
+		}
+
+		for host, expected := range map[string]string{"acme.app.com": "tenant acme", "app.com": "not found"} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://"+host+"/api/home", nil))
+			if w.Body.String() != expected {
+				t.Fatalf("%s: %s: expected: %q but got: %q", format, host, expected, w.Body.String())
+			}
+		}
+
+		if url, err := mux.URL("user", "id", "7"); err != nil || url != "/api/users/7" {
+			t.Fatalf("%s: expected the url of the user route but got: %q, %v", format, url, err)
+		}
+	}
+}
+
+func TestLoadMuxNestedGroups(t *testing.T) {
+	const data = `prefix: /api
+groups:
+  - prefix: /a
+    routes:
+      - {pattern: /users/:id, handler: user}
+    groups:
+      - prefix: /b
+        middlewares: [auth]
+        routes:
+          - {pattern: /status, handler: status}
+`
+
+	mux, err := LoadMux([]byte(data), testRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testHandler(t, mux, http.MethodGet, "/api/a/users/42").statusCode(http.StatusOK).bodyEq("user42")
+	testHandler(t, mux, http.MethodGet, "/api/a/b/status").statusCode(http.StatusOK).bodyEq("ok").headerEq("X-Auth", "1")
+}
+
+func TestLoadMuxErrors(t *testing.T) {
+	tests := []struct {
+		name, data, err string
+	}{
+		{"unknown handler", `routes: [{pattern: /, handler: missing}]`, `route "/": unknown handler "missing"`},
+		{"unknown middleware", "prefix: /api\ngroups:\n- prefix: /v1\n  middlewares: [missing]", `group "/api/v1": unknown middleware "missing"`},
+		{"unknown key", `{"routes": [{"pattern": "/", "handler": "user", "method": "GET"}]}`, `unknown field "method"`},
+		{"empty pattern", `routes: [{handler: user}]`, `empty pattern`},
+		{"invalid pattern", `routes: [{pattern: "/users/:id<unknown>", handler: user}]`, `unknown parameter constraint "unknown"`},
+		{"invalid host", `routes: [{pattern: /, handler: user, host: .app.com}]`, `route "/": invalid host pattern ".app.com"`},
+		{"pattern without slash", `routes: [{pattern: users, handler: user}]`, `route "users": ambiguous pattern "users"`},
+		{"host without slash", `routes: [{pattern: users, handler: user, host: app.com}]`, `route "users": the pattern of a host should start with a slash`},
+		{"invalid yaml", "routes:\n  - pattern: /\n      handler: user", `yaml: line 3: unexpected indentation`},
+	}
+
+	for _, tt := range tests {
+		if _, err := LoadMux([]byte(tt.data), testRegistry()); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s: expected an error which contains: %s but got: %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		yaml, json string
+	}{
+		{"", "null"},
+		{"a: 1\nb: [x, 'y''s', \"z\\n\"]\nc:\n  d: true\n  e: ~\n", `{"a":1,"b":["x","y's","z\n"],"c":{"d":true,"e":null}}`},
+		{"list:\n- a\n- b: 1\n  c: 2\n-\n  - nested\n", `{"list":["a",{"b":1,"c":2},["nested"]]}`},
+		{"url: http://app.com/#top # a comment\nempty:\nversion: 1.2.3\n", `{"url":"http://app.com/#top","empty":null,"version":"1.2.3"}`},
+		{"flow: {a: [1, 2], 'b c': {}}\n", `{"flow":{"a":[1,2],"b c":{}}}`},
+		{"- - 1\n  - 2\n- 3\n", `[[1,2],3]`},
+	}
+
+	for _, tt := range tests {
+		b, err := yamlToJSON([]byte(tt.yaml))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.yaml, err)
+		}
+		if string(b) != tt.json {
+			t.Fatalf("%q: expected: %s but got: %s", tt.yaml, tt.json, b)
+		}
+	}
+
+	for _, valid := range []string{"---\na: 1\n", "---\na: 1\n...\n"} {
+		if _, err := yamlToJSON([]byte(valid)); err != nil {
+			t.Fatalf("%q: %v", valid, err)
+		}
+	}
+}
+
+func TestYAMLToJSONUnsupported(t *testing.T) {
+	tests := []struct {
+		name, yaml, err string
+	}{
+		{"literal block scalar", "a: |\n  text", "unsupported value |"},
+		{"folded block scalar", "a: >\n  text", "unsupported value >"},
+		{"multi-line plain scalar", "a: first\n  second", "unexpected indentation"},
+		{"multi-line quoted scalar", "a: 'first\n  second'", "invalid single-quoted scalar"},
+		{"anchor", "a: &x 1\nb: 2", "unsupported value &x 1"},
+		{"alias", "a: 1\nb: *x", "unsupported value *x"},
+		{"tag", "a: !!str 1", "unsupported value !!str 1"},
+		{"complex key", "? a\n: 1", "complex mapping keys are not supported"},
+		{"duplicate key", "a: 1\na: 2", `duplicate key "a"`},
+		{"tab", "a:\n\t- b", "tabs are not allowed"},
+		{"multiple documents", "a: 1\n---\nb: 2", "multiple documents are not supported"},
+		{"document after end", "a: 1\n...\nb: 2", "multiple documents are not supported"},
+		{"unclosed flow", "a: [1, 2", "unclosed flow collection"},
+		{"unclosed quote", "a: 'b", "invalid single-quoted scalar"},
+		{"indentation", "a: 1\n  b: 2", "unexpected indentation"},
+	}
+
+	for _, tt := range tests {
+		if _, err := yamlToJSON([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s: expected an error which contains: %s but got: %v", tt.name, tt.err, err)
+		}
+	}
+}
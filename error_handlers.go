@@ -0,0 +1,100 @@
+package muxie
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// errorHandlers holds the NotFound and MethodNotAllowed handlers of a Mux and its sub muxes.
+// The handlers are resolved by the longest root path of a (sub) Mux that the request path belongs to,
+// a sub Mux without its own handlers inherits the handlers of its parent, unless it is unlinked.
+type errorHandlers struct {
+	mu sync.Mutex
+	// entries holds an []*errorHandlersEntry sorted by the length of their root, longest first.
+	// It is replaced on each change, so it can be read without locking.
+	entries atomic.Value
+}
+
+type errorHandlersEntry struct {
+	root string
+
+	// notFound is wrapped with the middlewares of the Mux that registered it.
+	notFound http.Handler
+	// methodNotAllowed is not wrapped, it is called by the route's `MethodHandler`
+	// which already runs through the route's middlewares.
+	methodNotAllowed http.Handler
+
+	// if true then the handlers of the parent mux are not inherited.
+	unlinked bool
+}
+
+func newErrorHandlers() *errorHandlers {
+	e := new(errorHandlers)
+	e.entries.Store([]*errorHandlersEntry(nil))
+	return e
+}
+
+// update calls "fn" with a copy of the "root"'s entry and stores it.
+func (e *errorHandlers) update(root string, fn func(entry *errorHandlersEntry)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	entries := e.entries.Load().([]*errorHandlersEntry)
+	newEntries := make([]*errorHandlersEntry, 0, len(entries)+1)
+	entry := &errorHandlersEntry{root: root}
+	for _, existing := range entries {
+		if existing.root == root {
+			*entry = *existing
+			continue
+		}
+
+		newEntries = append(newEntries, existing)
+	}
+
+	fn(entry)
+	newEntries = append(newEntries, entry)
+	sort.SliceStable(newEntries, func(i, j int) bool {
+		return len(newEntries[i].root) > len(newEntries[j].root)
+	})
+
+	e.entries.Store(newEntries)
+}
+
+// get returns the first handler that "field" returns for the entries that the "path" belongs to,
+// starting from the longest root. Returns nil if the default handler should be used instead.
+func (e *errorHandlers) get(path string, field func(entry *errorHandlersEntry) http.Handler) http.Handler {
+	if e == nil {
+		return nil
+	}
+
+	for _, entry := range e.entries.Load().([]*errorHandlersEntry) {
+		if root := entry.root; root != "" && path != root && !strings.HasPrefix(path, root+pathSep) {
+			continue
+		}
+
+		if h := field(entry); h != nil {
+			return h
+		}
+
+		if entry.unlinked {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (e *errorHandlers) notFound(path string) http.Handler {
+	return e.get(path, func(entry *errorHandlersEntry) http.Handler {
+		return entry.notFound
+	})
+}
+
+func (e *errorHandlers) methodNotAllowed(path string) http.Handler {
+	return e.get(path, func(entry *errorHandlersEntry) http.Handler {
+		return entry.methodNotAllowed
+	})
+}
@@ -0,0 +1,270 @@
+package muxie
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MatchRule is the rule of the `Trie#Search` priority which matched a path segment, see `Explain`.
+type MatchRule string
+
+const (
+	// MatchStatic is a static path segment.
+	MatchStatic MatchRule = "static"
+	// MatchPrefixParam is a prefix parameter, i.e "img+:name".
+	MatchPrefixParam MatchRule = "prefix parameter"
+	// MatchSuffixParam is a suffix parameter, i.e "name-:.txt".
+	MatchSuffixParam MatchRule = "suffix parameter"
+	// MatchMixedParam is a path segment with mixed parameters and literal text, i.e ":name.:ext".
+	MatchMixedParam MatchRule = "mixed parameters"
+	// MatchConstrainedParam is a constrained named parameter, i.e ":id<int>".
+	MatchConstrainedParam MatchRule = "constrained parameter"
+	// MatchNamedParam is a named parameter, i.e ":name".
+	MatchNamedParam MatchRule = "named parameter"
+	// MatchWildcard is a wildcard, i.e "*path", it takes the rest of the path.
+	MatchWildcard MatchRule = "wildcard"
+	// MatchUnvisitedParam is a named parameter of a previous path segment which was matched
+	// by a static path segment, the search goes back to it, see `TrieOptions.SearchUnvisitedParams`.
+	MatchUnvisitedParam MatchRule = "unvisited parameter"
+	// MatchBacktrack means that the node matched by the path segment did not lead to a complete node,
+	// the search goes back to try the next candidate, see `TrieOptions.Backtracking`.
+	MatchBacktrack MatchRule = "backtrack"
+	// MatchClosestWildcard is the wildcard of the closest parent, the search did not find a complete node.
+	MatchClosestWildcard MatchRule = "closest wildcard"
+	// MatchRootWildcard is the wildcard of the root, i.e "/*path", nothing else matched.
+	MatchRootWildcard MatchRule = "root wildcard"
+	// MatchRootSlash is the "/" path.
+	MatchRootSlash MatchRule = "root"
+	// MatchNone means that nothing matched.
+	MatchNone MatchRule = "none"
+)
+
+// ExplainStep is a decision of the `Trie#Search`, see `Explanation`.
+type ExplainStep struct {
+	// Segment is the path segment (or the rest of the path) that the step is about, i.e "42".
+	Segment string `json:"segment"`
+	// Candidates are the keys of the children that the segment could match, in order of priority,
+	// i.e ["list", ":<int>", ":"]. A static child is listed only if its key is the segment.
+	Candidates []string `json:"candidates,omitempty"`
+	// Rule is the priority rule that decided the step.
+	Rule MatchRule `json:"rule"`
+	// Key is the key of the matched node, i.e ":<int>", if any.
+	Key string `json:"key,omitempty"`
+}
+
+// ExplanationOf is the decision trace of a `Trie#Search`, see `Trie#Explain`.
+type ExplanationOf[T any] struct {
+	Path  string        `json:"path"`
+	Steps []ExplainStep `json:"steps"`
+	// Node is the found node, nil if not found.
+	Node *NodeOf[T] `json:"-"`
+	// Pattern is the path pattern of the found node, empty if not found.
+	Pattern string       `json:"pattern"`
+	Params  []ParamEntry `json:"params"`
+}
+
+// Explanation is the decision trace of a search of the `Trie`.
+type Explanation = ExplanationOf[interface{}]
+
+// Explain searches the "path" like the `Search` does and returns the decisions that led to its result:
+// the path segments, the children that each one could match, the priority rule that won,
+// the backtracking to unvisited parameters and the fallbacks to the wildcards.
+// It is slower than `Search`, it is meant for debugging, see `Mux#HandleExplain` too.
+func (t *TrieOf[T]) Explain(path string) *ExplanationOf[T] {
+	e := &ExplanationOf[T]{Path: path}
+
+	pw := new(Writer)
+	e.Node = t.search(path, pw, e)
+	if e.Node != nil {
+		e.Pattern = e.Node.key
+		e.Params = pw.GetAll()
+	}
+
+	return e
+}
+
+// String returns the explanation as text, one line per step.
+func (e *ExplanationOf[T]) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "path: %s\n", e.Path)
+	for i, step := range e.Steps {
+		fmt.Fprintf(&b, "%d. %q: %s", i+1, step.Segment, step.Rule)
+		if step.Key != "" {
+			fmt.Fprintf(&b, " %q", step.Key)
+		}
+		if len(step.Candidates) > 0 {
+			fmt.Fprintf(&b, " (candidates: %s)", strings.Join(step.Candidates, ", "))
+		}
+		b.WriteByte('\n')
+	}
+
+	if e.Node == nil {
+		b.WriteString("result: not found\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "result: %s\n", e.Pattern)
+	for _, p := range e.Params {
+		fmt.Fprintf(&b, "param: %s=%s\n", p.Key, p.Value)
+	}
+
+	return b.String()
+}
+
+// segment adds a step for the path segment, "value" is the original segment and "s" is the one to search for,
+// they differ on case-insensitive tries.
+func (e *ExplanationOf[T]) segment(n *NodeOf[T], value, s string) {
+	if e == nil {
+		return
+	}
+
+	e.Steps = append(e.Steps, ExplainStep{Segment: value, Candidates: n.candidateKeys(s)})
+}
+
+// match completes the last step with the "rule" that matched the "child" node.
+func (e *ExplanationOf[T]) match(rule MatchRule, child *NodeOf[T]) {
+	if e == nil || len(e.Steps) == 0 {
+		return
+	}
+
+	step := &e.Steps[len(e.Steps)-1]
+	step.Rule = rule
+	step.Key = child.segmentKey()
+}
+
+// attempt completes the last step with the "rule" that matched the "child" node for the "value",
+// or adds a new step if the last one is already completed, a backtracking search tries more than one candidate per segment.
+func (e *ExplanationOf[T]) attempt(rule MatchRule, child *NodeOf[T], value string) {
+	if e == nil {
+		return
+	}
+
+	if len(e.Steps) == 0 || e.Steps[len(e.Steps)-1].Rule != "" {
+		e.Steps = append(e.Steps, ExplainStep{Segment: value})
+	}
+
+	step := &e.Steps[len(e.Steps)-1]
+	step.Segment = value
+	step.Rule = rule
+	step.Key = child.segmentKey()
+}
+
+// tail completes the last step with the "value" of the path segments that the compressed node matched.
+func (e *ExplanationOf[T]) tail(value string) {
+	if e == nil || len(e.Steps) == 0 {
+		return
+	}
+
+	e.Steps[len(e.Steps)-1].Segment = value
+}
+
+// backtrack adds a step for a path segment which is matched by an unvisited named parameter.
+func (e *ExplanationOf[T]) backtrack(n *NodeOf[T], value string) {
+	if e == nil {
+		return
+	}
+
+	e.Steps = append(e.Steps, ExplainStep{Segment: value, Rule: MatchUnvisitedParam, Key: n.segmentKey()})
+}
+
+// fallback adds a step for a decision which is not about a single path segment, i.e the closest wildcard.
+func (e *ExplanationOf[T]) fallback(rule MatchRule, value string) {
+	if e == nil {
+		return
+	}
+
+	e.Steps = append(e.Steps, ExplainStep{Segment: value, Rule: rule})
+}
+
+// segmentKey returns the key of this node under its parent, used for debugging.
+// The key of a compressed node contains its tail, i.e "api/v1".
+func (n *NodeOf[T]) segmentKey() string {
+	if n == nil || n.parent == nil {
+		return ""
+	}
+
+	s, _ := n.parent.children.keyOf(n)
+	if n.tail != "" {
+		s += pathSep + n.tail
+	}
+	return s
+}
+
+// candidateKeys returns the keys of the children that the path segment "s" could match, in order of priority.
+func (n *NodeOf[T]) candidateKeys(s string) (keys []string) {
+	if n.hasChild(s) {
+		keys = append(keys, s)
+	}
+
+	for _, l := range n.childPrefixLengths {
+		if l <= len(s) && n.hasChild(s[:l]+PrefixParamStart) {
+			keys = append(keys, s[:l]+PrefixParamStart)
+		}
+	}
+
+	for _, l := range n.childSuffixLengths {
+		if l <= len(s) && n.hasChild(SuffixParamStart+s[len(s)-l:]) {
+			keys = append(keys, SuffixParamStart+s[len(s)-l:])
+		}
+	}
+
+	var buf [8]string
+	for _, key := range n.childMixedParameters {
+		if _, ok := n.getChild(key).mixed.match(s, s, buf[:0]); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	keys = append(keys, n.childConstrainedParameters...)
+
+	if n.childNamedParameter {
+		keys = append(keys, ParamStart)
+	}
+
+	if n.childWildcardParameter {
+		keys = append(keys, WildcardParamStart)
+	}
+
+	return
+}
+
+// HandleExplain registers a debug route which explains how this Mux' routes match a path,
+// the path is given by the "path" URL query parameter, i.e "/debug/routes?path=/users/42".
+// The path is resolved like the pattern of the `Handle`: it is relative to the prefix of a sub mux
+// and it may start with a host pattern to explain the routes of that host pattern, i.e ":tenant.app.com/users/42".
+// The explanation is sent as text, or as JSON if the "format" URL query parameter is "json".
+//
+// It is opt-in because it exposes the routes, it should not be registered on public servers.
+// See `Trie#Explain`.
+func (m *Mux) HandleExplain(pattern string) {
+	m.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		if path == "" {
+			http.Error(w, "missing the path URL query parameter", http.StatusBadRequest)
+			return
+		}
+
+		routes, path, err := m.lookupRoutes(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if routes == nil {
+			http.Error(w, "no routes for the host pattern of the path", http.StatusNotFound)
+			return
+		}
+
+		e := routes.Explain(path)
+		if r.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			json.NewEncoder(w).Encode(e)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, e.String())
+	}), WithOpenAPI(OpenAPIOperation{Hidden: true}))
+}
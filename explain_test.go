@@ -0,0 +1,101 @@
+package muxie
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTrieExplain(t *testing.T) {
+	tree := NewTrieWithOptions(TrieOptions{SearchUnvisitedParams: true})
+	for _, pattern := range []string{
+		"/users/list", "/users/:id<int>", "/users/:name", "/users/:name/friends",
+		"/files/*path", "/files/readme/static", "/a/b/c/z", "/a/:p1/c/d", "/",
+	} {
+		tree.Insert(pattern, WithHandler(http.NotFoundHandler()))
+	}
+
+	rules := func(e *Explanation) (rules []MatchRule) {
+		for _, step := range e.Steps {
+			rules = append(rules, step.Rule)
+		}
+		return
+	}
+
+	tests := []struct {
+		path    string
+		rules   []MatchRule
+		pattern string
+		params  []ParamEntry
+	}{
+		{"/", []MatchRule{MatchRootSlash}, "/", nil},
+		{"/users/42", []MatchRule{MatchStatic, MatchConstrainedParam}, "/users/:id<int>", []ParamEntry{{"id", "42"}}},
+		{"/users/kataras/friends", []MatchRule{MatchStatic, MatchNamedParam, MatchStatic}, "/users/:name/friends", []ParamEntry{{"name", "kataras"}}},
+		{"/a/b/c/d", []MatchRule{MatchStatic, MatchStatic, MatchStatic, MatchNone, MatchUnvisitedParam, MatchStatic, MatchStatic}, "/a/:p1/c/d", []ParamEntry{{"p1", "b"}}},
+		{"/files/readme/other", []MatchRule{MatchStatic, MatchStatic, MatchNone, MatchClosestWildcard}, "/files/*path", []ParamEntry{{"path", "readme/other"}}},
+		{"/unknown", []MatchRule{MatchNone, MatchNone}, "", nil},
+	}
+
+	for _, tt := range tests {
+		e := tree.Explain(tt.path)
+		if got := rules(e); !reflect.DeepEqual(tt.rules, got) {
+			t.Fatalf("%s: expected rules: %v but got: %v\n%s", tt.path, tt.rules, got, e)
+		}
+
+		if e.Pattern != tt.pattern || !reflect.DeepEqual(tt.params, e.Params) {
+			t.Fatalf("%s: expected %s with %v but got %s with %v\n%s", tt.path, tt.pattern, tt.params, e.Pattern, e.Params, e)
+		}
+
+		var w Writer
+		if n := tree.Search(tt.path, &w); n != e.Node {
+			t.Fatalf("%s: expected Explain to find the same node as Search: %v but got: %v", tt.path, n, e.Node)
+		}
+	}
+
+	step := tree.Explain("/users/42").Steps[1]
+	if expected := []string{":<int>", ":"}; step.Key != ":<int>" || !reflect.DeepEqual(expected, step.Candidates) {
+		t.Fatalf("unexpected step: %#v", step)
+	}
+}
+
+func TestMuxHandleExplain(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleExplain("/debug/routes")
+
+	testHandler(t, mux, http.MethodGet, "/debug/routes?path=/users/42").statusCode(http.StatusOK).
+		bodyEq(`path: /users/42
+1. "users": static "users" (candidates: users)
+2. "42": named parameter ":" (candidates: :)
+result: /users/:id
+param: id=42
+`)
+
+	testHandler(t, mux, http.MethodGet, "/debug/routes").statusCode(http.StatusBadRequest)
+
+	// the path is resolved like the pattern of the Handle, relative to the sub mux and with a host pattern.
+	v1 := mux.Of("/v1")
+	v1.HandleFunc("/items/:id", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc(":tenant.app.com/projects/:id", func(w http.ResponseWriter, r *http.Request) {})
+	v1.(*Mux).HandleExplain("/debug/routes")
+
+	testHandler(t, mux, http.MethodGet, "/v1/debug/routes?path=/items/42").statusCode(http.StatusOK).
+		bodyEq(`path: /v1/items/42
+1. "v1": static "v1" (candidates: v1)
+2. "items": static "items" (candidates: items)
+3. "42": named parameter ":" (candidates: :)
+result: /v1/items/:id
+param: id=42
+`)
+
+	testHandler(t, mux, http.MethodGet, "/debug/routes?path={tenant}.app.com/projects/42").statusCode(http.StatusOK).
+		bodyEq(`path: /projects/42
+1. "projects": static "projects" (candidates: projects)
+2. "42": named parameter ":" (candidates: :)
+result: /projects/:id
+param: id=42
+`)
+
+	testHandler(t, mux, http.MethodGet, "/debug/routes?path=admin.app.com/projects/42").statusCode(http.StatusNotFound)
+	testHandler(t, mux, http.MethodGet, "/debug/routes?path=projects/42").statusCode(http.StatusBadRequest)
+}
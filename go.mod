@@ -1,3 +1,3 @@
 module github.com/kivera-io/muxie
 
-go 1.15
+go 1.18
@@ -0,0 +1,384 @@
+package muxie
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// hostRoutes are the route tables of the host patterns, see `Mux#Handle`.
+// The host patterns are stored to a host trie per port, the labels of a host are its path segments in reverse order,
+// i.e ":tenant.app.com" is stored as "/com/app/:tenant", so the wildcard of "*.api.app.com" is the last segment.
+// Each node of a host trie holds the route table of its host pattern.
+// They are shared between a Mux and its sub muxes.
+type hostRoutes struct {
+	mu sync.Mutex
+	// holds a map[string]*hostTrie of the host tries by port, "" for the host patterns without a port,
+	// it is replaced on each new port, so the ServeHTTP can read it without locking.
+	ports atomic.Value
+}
+
+// hostEntry is the data of a host trie's node.
+type hostEntry struct {
+	// the host pattern as it was registered, i.e ":tenant.app.com:8080".
+	pattern string
+	// the names of the host parameters.
+	params []string
+	routes *Trie
+}
+
+// hostTrie is the trie of the host patterns, its nodes hold their route tables.
+type hostTrie = TrieOf[*hostEntry]
+
+// splitHostPattern splits a route pattern to its host pattern, if any, and its path pattern,
+// i.e ":tenant.app.com/projects/:id" to ":tenant.app.com" and "/projects/:id".
+// A pattern that does not start with a slash should start with an explicit host pattern, see `isExplicitHost`,
+// otherwise it is ambiguous, i.e the "users/:id", and an error is returned.
+func splitHostPattern(pattern string) (host, path string, err error) {
+	if pattern == "" || pattern[0] == pathSepB || pattern[0] == OptionalStart[0] {
+		return "", pattern, nil
+	}
+
+	host, path = pattern, pathSep
+	if i := strings.IndexByte(pattern, pathSepB); i != -1 {
+		host, path = pattern[:i], pattern[i:]
+	}
+
+	if !isExplicitHost(host) {
+		return "", "", fmt.Errorf("ambiguous pattern %q, a path pattern should start with a slash and a host pattern should have a dot or a port, i.e \"localhost./\"", pattern)
+	}
+
+	return host, path, nil
+}
+
+// isExplicitHost reports whether the "host" is a host pattern and not a path segment without a slash,
+// it should have a dot, i.e "app.com" or the "localhost." of a single label, or a port, i.e "localhost:8080".
+func isExplicitHost(host string) bool {
+	if strings.IndexByte(host, '.') != -1 {
+		return true
+	}
+
+	i := strings.LastIndexByte(host, ':')
+	return i > 0 && isUintParam(host[i+1:])
+}
+
+// parseHostPattern converts a host pattern to the path pattern of the host trie and its port, if any.
+// The labels of the host can be named parameters, ":tenant" or "{tenant}", and the first label can be a wildcard,
+// "*" or "*name", which matches one or more labels, i.e "*.api.app.com".
+func parseHostPattern(host string) (path, port string, params []string, err error) {
+	if i := strings.LastIndexByte(host, ':'); i > 0 && i < len(host)-1 && isUintParam(host[i+1:]) {
+		host, port = host[:i], host[i+1:]
+	}
+
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || host == WildcardParamStart {
+		return "", "", nil, fmt.Errorf("host pattern %q should have at least one label which is not a wildcard", host)
+	}
+
+	labels := strings.Split(host, ".")
+	var b strings.Builder
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := labels[i]
+		if len(label) > 2 && label[0] == '{' && label[len(label)-1] == '}' {
+			label = ParamStart + label[1:len(label)-1]
+		}
+
+		switch {
+		case label == "":
+			return "", "", nil, fmt.Errorf("host pattern %q: empty label", host)
+		case label[0] == WildcardParamStart[0]:
+			if i > 0 {
+				return "", "", nil, fmt.Errorf("host pattern %q: wildcard %q is not the first label", host, label)
+			}
+			params = append(params, label[1:])
+		case label[0] == ParamStart[0]:
+			name, _ := splitParamConstraint(label[1:])
+			params = append(params, name)
+		}
+
+		b.WriteString(pathSep)
+		b.WriteString(label)
+	}
+
+	return b.String(), port, params, nil
+}
+
+// splitHostPort splits the host of a request to its name, without a trailing dot, and its port, if any.
+func splitHostPort(host string) (name, port string) {
+	if i := strings.LastIndexByte(host, ':'); i != -1 && strings.IndexByte(host[i:], ']') == -1 {
+		host, port = host[:i], host[i+1:]
+	}
+
+	return strings.TrimSuffix(host, "."), port
+}
+
+// hostPath returns the path of the host trie for the host name, its labels in reverse order.
+func hostPath(name string) string {
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for end := len(name); end > 0; {
+		i := strings.LastIndexByte(name[:end], '.')
+		b.WriteString(pathSep)
+		b.WriteString(name[i+1 : end])
+		end = i
+	}
+
+	return b.String()
+}
+
+// hostParams stores the host parameters, the path segments of a wildcard are converted back to labels.
+type hostParams struct {
+	params ParamsSetter
+}
+
+func (p hostParams) Set(key, value string) {
+	if key == "" {
+		return
+	}
+
+	if strings.IndexByte(value, pathSepB) != -1 {
+		labels := strings.Split(value, pathSep)
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		value = strings.Join(labels, ".")
+	}
+
+	p.params.Set(key, value)
+}
+
+func (h *hostRoutes) load() map[string]*hostTrie {
+	ports, _ := h.ports.Load().(map[string]*hostTrie)
+	return ports
+}
+
+// stage returns a copy of the host route tables, its changes are not visible to this one,
+// the `Mux#Batch` publishes them at once with the routes without a host.
+// The caller should hold the "mu" until the copy is published, so the changes of this one are not lost.
+func (h *hostRoutes) stage() *hostRoutes {
+	ports := h.load()
+	staged := make(map[string]*hostTrie, len(ports))
+	for port, hosts := range ports {
+		hosts = hosts.clone()
+		// the nodes of the copy are not visible yet, their entries are replaced with ones of copied route tables.
+		walkNode(hosts.tree.Load().(*trieTree[*hostEntry]).root, func(n *NodeOf[*hostEntry]) error {
+			e := *n.Data
+			e.routes = e.routes.clone()
+			n.Data = &e
+			return nil
+		})
+		staged[port] = hosts
+	}
+
+	s := new(hostRoutes)
+	s.ports.Store(staged)
+	return s
+}
+
+// routes returns the route table of the "host" pattern, it is created if "create" is true,
+// the "options" are the options of its trie.
+// The caller should hold the "mu" if "create" is true or it writes to the route table, see `Mux#withRoutes`.
+func (h *hostRoutes) routes(host string, create bool, options TrieOptions) (*Trie, error) {
+	path, port, params, err := parseHostPattern(host)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := h.load()
+	hosts := ports[port]
+	if hosts == nil {
+		if !create {
+			return nil, nil
+		}
+
+		hosts = NewTrieOfWithOptions[*hostEntry](TrieOptions{CaseInsensitive: true})
+		newPorts := make(map[string]*hostTrie, len(ports)+1)
+		for p, t := range ports {
+			newPorts[p] = t
+		}
+		newPorts[port] = hosts
+		h.ports.Store(newPorts)
+	}
+
+	if n := hosts.lookup(hosts.load().root, slowPathSplit(path)); n != nil && n.end {
+		return n.Data.routes, nil
+	}
+
+	if !create {
+		return nil, nil
+	}
+
+	routes := NewTrieWithOptions(options)
+	hosts.Insert(path, WithDataOf(&hostEntry{pattern: host, params: params, routes: routes}))
+	return routes, nil
+}
+
+// search searches the route tables of the host patterns which match the "host" of a request for the "path",
+// the parameters of both are stored to the "params". The host patterns with the request's port are preferred.
+// It returns the route table of the node and the number of the host parameters as well.
+func (h *hostRoutes) search(host, path string, params *Writer) (*Node, *Trie, int) {
+	ports := h.load()
+	if len(ports) == 0 {
+		return nil, nil, 0
+	}
+
+	name, port := splitHostPort(host)
+	key := hostPath(name)
+	if port != "" {
+		if n, routes, hostParamsLen := searchHost(ports[port], key, path, params); n != nil {
+			return n, routes, hostParamsLen
+		}
+	}
+
+	return searchHost(ports[""], key, path, params)
+}
+
+// searchHost searches the "hosts" trie for the host trie's path "key" and its route table for the "path".
+// If the table of the most specific host pattern has no route for the "path" then the tables of the rest
+// of the host patterns that the "key" matches are searched, in order of specificity,
+// i.e "*.app.com" after ":tenant.app.com" for the "foo.app.com".
+func searchHost(hosts *hostTrie, key, path string, params *Writer) (*Node, *Trie, int) {
+	if hosts == nil {
+		return nil, nil, 0
+	}
+
+	n := hosts.Search(key, hostParams{params})
+	if n != nil {
+		hostParamsLen := len(params.params)
+		if found := n.Data.routes.Search(path, params); found != nil {
+			return found, n.Data.routes, hostParamsLen
+		}
+	}
+
+	// drop the host parameters.
+	params.params = params.params[0:0]
+
+	for _, match := range hosts.SearchAll(key) {
+		if match.Node == n {
+			continue
+		}
+
+		for _, p := range match.Params {
+			hostParams{params}.Set(p.Key, p.Value)
+		}
+
+		hostParamsLen := len(params.params)
+		if found := match.Node.Data.routes.Search(path, params); found != nil {
+			return found, match.Node.Data.routes, hostParamsLen
+		}
+
+		params.params = params.params[0:0]
+	}
+
+	return nil, nil, 0
+}
+
+// each calls the "fn" for each host pattern, sorted by port and host pattern.
+func (h *hostRoutes) each(fn func(e *hostEntry)) {
+	ports := h.load()
+	keys := make([]string, 0, len(ports))
+	for port := range ports {
+		keys = append(keys, port)
+	}
+	sort.Strings(keys)
+
+	for _, port := range keys {
+		var entries []*hostEntry
+		ports[port].Walk(func(n *NodeOf[*hostEntry]) error {
+			entries = append(entries, n.Data)
+			return nil
+		})
+		sort.Slice(entries, func(i, j int) bool { return entries[i].pattern < entries[j].pattern })
+
+		for _, e := range entries {
+			fn(e)
+		}
+	}
+}
+
+// withRoutes calls "fn" with the route table of the "pattern" and its path pattern with the root of this Mux,
+// the table of the `Routes` if the pattern has no host, i.e ":tenant.app.com/projects/:id".
+// The "fn" is not called if the table does not exist and "create" is false.
+// It panics on an ambiguous or invalid host pattern, the "method" is the name of the Mux's method for the panic message.
+//
+// The route table of a host pattern is written while the host tables are locked,
+// so a `Batch` can not publish a copy of the table that misses the changes of "fn".
+func (m *Mux) withRoutes(method, pattern string, create bool, fn func(routes *Trie, path string)) {
+	host, path, err := splitHostPattern(pattern)
+	if err != nil {
+		panic("muxie/Mux#" + method + ": " + err.Error())
+	}
+
+	if host == "" {
+		fn(m.Routes, m.root+path)
+		return
+	}
+
+	m.hosts.mu.Lock()
+	defer m.hosts.mu.Unlock()
+
+	routes, err := m.hosts.routes(host, create, m.Routes.options())
+	if err != nil {
+		panic("muxie/Mux#" + method + ": " + err.Error())
+	}
+
+	if routes != nil {
+		fn(routes, m.root+path)
+	}
+}
+
+// lookupRoutes returns the route table of the "pattern" and its path pattern with the root of this Mux, see `withRoutes`.
+// The table is nil if the host pattern of the "pattern" has no routes.
+func (m *Mux) lookupRoutes(pattern string) (*Trie, string, error) {
+	host, path, err := splitHostPattern(pattern)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if host == "" {
+		return m.Routes, m.root + path, nil
+	}
+
+	routes, err := m.hosts.routes(host, false, TrieOptions{})
+	return routes, m.root + path, err
+}
+
+// search searches the routes of the request's host first, see `Handle`, and then the routes without a host.
+// It returns the route table of the node and the number of the host parameters of the "params" as well.
+func (m *Mux) search(r *http.Request, path string, params *Writer) (*Node, *Trie, int) {
+	if n, routes, hostParamsLen := m.hosts.search(r.Host, path, params); n != nil {
+		return n, routes, hostParamsLen
+	}
+
+	return m.Routes.Search(path, params), m.Routes, 0
+}
+
+// searchNext returns the first route, other than the "n", that the "path" matches and which can serve the "r",
+// the routes of the "routes" table first, in order of specificity, and then the routes without a host.
+// Its parameters replace the path parameters of the "params". It returns nil if there is no such route.
+//
+// See `Mux#HandleWhen`.
+func (m *Mux) searchNext(r *http.Request, path string, n *Node, routes *Trie, hostParamsLen int, params *Writer) (*Node, http.Handler) {
+	for routes != nil {
+		for _, match := range routes.SearchAll(path) {
+			if match.Node == n || match.Node.pathValues && !match.Node.stdMatch(match.Params) {
+				continue
+			}
+
+			if handler := match.Node.HandlerForRequest(r); handler != nil || match.Node.methodFallback != nil {
+				params.params = append(params.params[:hostParamsLen], match.Params...)
+				return match.Node, handler
+			}
+		}
+
+		if routes == m.Routes {
+			break
+		}
+		routes, hostParamsLen = m.Routes, 0
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,190 @@
+package muxie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHostPattern(t *testing.T) {
+	tests := []struct {
+		host   string
+		path   string
+		port   string
+		params []string
+		err    bool
+	}{
+		{"app.com", "/com/app", "", nil, false},
+		{":tenant.app.com", "/com/app/:tenant", "", []string{"tenant"}, false},
+		{"{tenant}.app.com:8080", "/com/app/:tenant", "8080", []string{"tenant"}, false},
+		{"*.api.app.com", "/com/app/api/*", "", []string{""}, false},
+		{"*sub.:region.app.com", "/com/app/:region/*sub", "", []string{"region", "sub"}, false},
+		{"localhost:8080", "/localhost", "8080", nil, false},
+		{"*", "", "", nil, true},
+		{"api.*.app.com", "", "", nil, true},
+		{"api..app.com", "", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		path, port, params, err := parseHostPattern(tt.host)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("%s: expected an error", tt.host)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", tt.host, err)
+		}
+
+		if path != tt.path || port != tt.port || !reflect.DeepEqual(params, tt.params) {
+			t.Fatalf("%s: expected: %s, %s, %v but got: %s, %s, %v", tt.host, tt.path, tt.port, tt.params, path, port, params)
+		}
+	}
+}
+
+func TestMuxHost(t *testing.T) {
+	mux := NewMux()
+	write := func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		for _, p := range GetParams(w) {
+			body += p.Key + "=" + p.Value + ";"
+		}
+		w.Write([]byte(body))
+	}
+
+	mux.HandleFunc(":tenant.app.com/projects/:id", write, WithTag("project"))
+	mux.HandleFunc("{tenant}.app.com/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("about " + GetParam(w, "tenant")))
+	})
+	mux.HandleFunc("admin.app.com/projects/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin " + GetParam(w, "id")))
+	})
+	mux.HandleFunc("*sub.api.app.com/status", write)
+	mux.HandleFunc("app.com:8080/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("port 8080"))
+	})
+	mux.HandleFunc("app.com", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("any port"))
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("about any host"))
+	})
+
+	tests := []struct {
+		host     string
+		path     string
+		expected string
+	}{
+		{"acme.app.com", "/projects/42", "tenant=acme;id=42;"},
+		{"ACME.App.com:443", "/projects/42", "tenant=ACME;id=42;"},
+		{"acme.app.com", "/about", "about acme"},
+		{"admin.app.com", "/projects/42", "admin 42"},
+		// the static host has no such route, the named parameter's one is next.
+		{"admin.app.com", "/about", "about admin"},
+		{"eu.west.api.app.com", "/status", "sub=eu.west;"},
+		{"app.com:8080", "/", "port 8080"},
+		{"app.com:9090", "/", "any port"},
+		{"app.com", "/", "any port"},
+		{"other.com", "/about", "about any host"},
+		{"acme.app.com.", "/projects/1", "tenant=acme;id=1;"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != tt.expected {
+			t.Fatalf("%s%s: expected body: %q but got: %q", tt.host, tt.path, tt.expected, got)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects/42", nil)
+	req.Host = "other.com"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected not found for a host without routes but got: %d", rec.Code)
+	}
+
+	if got, err := mux.URL("project", "tenant", "acme", "id", "42"); err != nil || got != "/projects/42" {
+		t.Fatalf("expected the path of the host route but got: %s (%v)", got, err)
+	}
+
+	var hosts []string
+	for _, route := range mux.ListRoutes() {
+		hosts = append(hosts, route.Host+route.Pattern)
+	}
+	expected := []string{
+		"/about",
+		"*sub.api.app.com/status",
+		":tenant.app.com/about",
+		":tenant.app.com/projects/:id",
+		"admin.app.com/projects/:id",
+		"app.com/",
+		"app.com:8080/",
+	}
+	if !reflect.DeepEqual(expected, hosts) {
+		t.Fatalf("expected routes: %v but got: %v", expected, hosts)
+	}
+
+	// the host patterns that a host matches are searched in order of specificity.
+	mux = NewMux()
+	mux.HandleFunc("*.app.com/x", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("x"))
+	})
+	mux.HandleFunc(":tenant.app.com/y", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("y " + GetParam(w, "tenant")))
+	})
+
+	for path, expected := range map[string]string{"/x": "x", "/y": "y foo"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = "foo.app.com"
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != expected {
+			t.Fatalf("foo.app.com%s: expected body: %q but got: %d %q", path, expected, rec.Code, got)
+		}
+	}
+}
+
+func TestMuxHostPatternExplicit(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	// a pattern without a slash is a host pattern only if it has a dot or a port.
+	for _, pattern := range []string{"users", "users/:id", ":id/files"} {
+		if panicked := catchPanic(func() { NewMux().HandleFunc(pattern, noop) }); !strings.Contains(panicked, "ambiguous pattern") {
+			t.Fatalf("%s: expected an ambiguous pattern panic but got: %q", pattern, panicked)
+		}
+	}
+
+	mux := NewMux()
+	mux.HandleFunc("localhost./status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("localhost"))
+	})
+	mux.HandleFunc("localhost:8080/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("localhost:8080"))
+	})
+
+	for host, expected := range map[string]string{"localhost": "localhost", "localhost:9090": "localhost", "localhost:8080": "localhost:8080"} {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != expected {
+			t.Fatalf("%s: expected body: %q but got: %q", host, expected, got)
+		}
+	}
+}
+
+func TestMuxRemoveHost(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc(":tenant.app.com/projects/:id", func(w http.ResponseWriter, r *http.Request) {})
+	if !mux.Remove(":tenant.app.com/projects/:id") || mux.Remove("unknown.app.com/projects/:id") {
+		t.Fatalf("expected only the registered host route to be removed")
+	}
+}
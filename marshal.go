@@ -0,0 +1,430 @@
+package muxie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// DataCodecOf encodes and decodes the `Data` of the nodes of a `TrieOf` when the trie is serialized,
+// see `TrieOf#DataCodec`.
+type DataCodecOf[T any] interface {
+	EncodeData(data T) ([]byte, error)
+	DecodeData(b []byte) (T, error)
+}
+
+// JSONDataCodec is the default `DataCodecOf`, it encodes the data as JSON.
+// The data of a `Trie` are decoded to the types of the `encoding/json` package, i.e a struct to a map[string]interface{},
+// set a codec which decodes to the original type with the `DataCodec` instead.
+type JSONDataCodec[T any] struct{}
+
+// EncodeData encodes the "data" as JSON.
+func (JSONDataCodec[T]) EncodeData(data T) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// DecodeData decodes the JSON "b" to a "T".
+func (JSONDataCodec[T]) DecodeData(b []byte) (data T, err error) {
+	err = json.Unmarshal(b, &data)
+	return
+}
+
+// HandlerResolver returns the handler of a route by its name when a serialized trie is loaded, see `TrieOf#HandlerResolver`.
+// The name of a route's handler is its `Tag`, or its path pattern if it has no tag.
+type HandlerResolver func(name string) (http.Handler, error)
+
+// DataCodec sets the codec of the nodes' `Data` for the `MarshalJSON`, `UnmarshalJSON`, `MarshalBinary` and `UnmarshalBinary`,
+// the default one is the `JSONDataCodec`.
+func (t *TrieOf[T]) DataCodec(codec DataCodecOf[T]) *TrieOf[T] {
+	t.codec = codec
+	return t
+}
+
+// HandlerResolver sets the "resolver" of the routes' handlers for the `UnmarshalJSON` and `UnmarshalBinary`,
+// a serialized trie with handlers can not be loaded without a resolver.
+// Only the `Node.Handler` is serialized, by its name, see `HandlerResolver` and `MarshalJSON`.
+func (t *TrieOf[T]) HandlerResolver(resolver HandlerResolver) *TrieOf[T] {
+	t.resolver = resolver
+	return t
+}
+
+// trieDocument is the portable form of a trie, the routes are in order of `Walk`.
+type trieDocument struct {
+	Options trieDocumentOptions `json:"options"`
+	Routes  []trieDocumentRoute `json:"routes"`
+}
+
+type trieDocumentOptions struct {
+	CaseInsensitive       bool `json:"caseInsensitive,omitempty"`
+	SearchUnvisitedParams bool `json:"searchUnvisitedParams,omitempty"`
+	Strict                bool `json:"strict,omitempty"`
+	Compress              bool `json:"compress,omitempty"`
+	Backtracking          bool `json:"backtracking,omitempty"`
+}
+
+type trieDocumentRoute struct {
+	Pattern string `json:"pattern"`
+	Tag     string `json:"tag,omitempty"`
+	// the name of the handler, empty if the route has no handler.
+	Handler string `json:"handler,omitempty"`
+	// the encoded data, nil if the route has no data, see `document`.
+	// It is stored as is to the JSON if it is valid JSON, otherwise as base64 to the "binaryData".
+	Data []byte `json:"-"`
+}
+
+type jsonRoute struct {
+	Pattern    string          `json:"pattern"`
+	Tag        string          `json:"tag,omitempty"`
+	Handler    string          `json:"handler,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	BinaryData []byte          `json:"binaryData,omitempty"`
+}
+
+func (r trieDocumentRoute) MarshalJSON() ([]byte, error) {
+	v := jsonRoute{Pattern: r.Pattern, Tag: r.Tag, Handler: r.Handler}
+	if json.Valid(r.Data) {
+		v.Data = r.Data
+	} else {
+		v.BinaryData = r.Data
+	}
+
+	return json.Marshal(v)
+}
+
+func (r *trieDocumentRoute) UnmarshalJSON(b []byte) error {
+	var v jsonRoute
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*r = trieDocumentRoute{Pattern: v.Pattern, Tag: v.Tag, Handler: v.Handler, Data: v.BinaryData}
+	if len(v.Data) > 0 && !bytes.Equal(v.Data, []byte("null")) {
+		r.Data = v.Data
+	}
+
+	return nil
+}
+
+func (t *TrieOf[T]) dataCodec() DataCodecOf[T] {
+	if t.codec == nil {
+		return JSONDataCodec[T]{}
+	}
+
+	return t.codec
+}
+
+// document returns the portable form of this trie, a pattern with optional parts is stored once, as it was inserted.
+func (t *TrieOf[T]) document() (*trieDocument, error) {
+	options := t.options()
+	doc := &trieDocument{Options: trieDocumentOptions(options)}
+	codec := t.dataCodec()
+
+	seen := make(map[string]struct{})
+	err := t.Walk(func(n *NodeOf[T]) error {
+		if _, ok := seen[n.key]; ok {
+			return nil
+		}
+		seen[n.key] = struct{}{}
+
+		if state := unserializableState(n); state != "" {
+			return fmt.Errorf("muxie: route %q: %s can not be serialized", n.key, state)
+		}
+
+		r := trieDocumentRoute{Pattern: n.key, Tag: n.Tag}
+		if n.Handler != nil {
+			r.Handler = handlerName(n)
+		}
+
+		// the zero data, i.e a nil pointer, is not stored, the node of a loaded trie has it anyway.
+		if !reflect.ValueOf(&n.Data).Elem().IsZero() {
+			data, err := codec.EncodeData(n.Data)
+			if err != nil {
+				return fmt.Errorf("muxie: route %q: encode data: %w", n.key, err)
+			}
+			r.Data = data
+		}
+
+		doc.Routes = append(doc.Routes, r)
+		return nil
+	})
+
+	return doc, err
+}
+
+// unserializableState returns the description of the state of the "n" that the serialized form does not keep, if any.
+// Only the pattern, the tag, the `Handler` by name and the data of a route are serialized,
+// the routes of the `Mux#HandleMethod`, `Mux#HandleWhen` and `Mux#HandleStd` and the ones with Mux middlewares have more.
+func unserializableState[T any](n *NodeOf[T]) string {
+	switch {
+	case len(n.methodHandlers) > 0 || n.methodFallback != nil:
+		return "the handlers per method"
+	case n.methodHandler != nil:
+		return "the MethodHandler"
+	case len(n.matchedHandlers) > 0 || n.notAcceptable != nil:
+		return "the conditional handlers"
+	case n.middlewares > 0:
+		return "the handler wrapped by middlewares"
+	case n.pathValues:
+		return "the net/http pattern"
+	}
+
+	return ""
+}
+
+// handlerName returns the name of the handler of the "n", see `HandlerResolver`.
+func handlerName[T any](n *NodeOf[T]) string {
+	if n.Tag != "" {
+		return n.Tag
+	}
+
+	return n.key
+}
+
+// loadDocument replaces the routes and the options of this trie with the ones of the "doc".
+// The routes are inserted to a new tree which is published at once, nothing changes on error.
+func (t *TrieOf[T]) loadDocument(doc *trieDocument) error {
+	options := TrieOptions(doc.Options)
+	codec := t.dataCodec()
+
+	// insert to a trie which is not strict, the conflicts are kept as warnings like on the original trie.
+	src := NewTrieOfWithOptions[T](TrieOptions{CaseInsensitive: options.CaseInsensitive, Compress: options.Compress})
+	for _, r := range doc.Routes {
+		if r.Pattern == "" {
+			return errors.New("muxie: empty pattern")
+		}
+
+		opts := []InsertOptionOf[T]{WithTagOf[T](r.Tag)}
+		if r.Handler != "" {
+			if t.resolver == nil {
+				return fmt.Errorf("muxie: route %q: no resolver for the handler %q", r.Pattern, r.Handler)
+			}
+
+			handler, err := t.resolver(r.Handler)
+			if err != nil {
+				return fmt.Errorf("muxie: route %q: resolve handler %q: %w", r.Pattern, r.Handler, err)
+			}
+			opts = append(opts, func(n *NodeOf[T]) { n.Handler = handler })
+		}
+
+		if r.Data != nil {
+			data, err := codec.DecodeData(r.Data)
+			if err != nil {
+				return fmt.Errorf("muxie: route %q: decode data: %w", r.Pattern, err)
+			}
+			opts = append(opts, WithDataOf(data))
+		}
+
+		if err := src.tryInsertAny(r.Pattern, opts); err != nil {
+			return err
+		}
+	}
+
+	tree := src.tree.Load().(*trieTree[T])
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.caseInsensitive = options.CaseInsensitive
+	t.searchUnvisitedParams = options.SearchUnvisitedParams
+	t.strict = options.Strict
+	t.compress = options.Compress
+	t.backtracking = options.Backtracking
+	t.tree.Store(tree)
+	return nil
+}
+
+// tryInsertAny inserts the "pattern" like the `Insert`, even if it is ambiguous, but it returns an error instead of a panic.
+func (t *TrieOf[T]) tryInsertAny(pattern string, options []InsertOptionOf[T]) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("muxie: route %q: %v", pattern, v)
+		}
+	}()
+
+	t.Insert(pattern, options...)
+	return nil
+}
+
+// MarshalJSON encodes the routes of this trie, their tags, their handlers by name and their data, see `DataCodec`,
+// and its options, so the `UnmarshalJSON` can load an identical trie, i.e in another process:
+// {"options":{"caseInsensitive":true},"routes":[{"pattern":"/orgs/:org/*path","tag":"org","data":{"rules":["audit"]}}]}
+//
+// Only the `Node.Handler` of a route is serialized, it returns an error if a route has more state, i.e
+// the handlers per method of the `Mux#HandleMethod`, the conditional handlers of the `Mux#HandleWhen`,
+// the routes of the `Mux#HandleStd` or a handler that is wrapped by the Mux middlewares.
+func (t *TrieOf[T]) MarshalJSON() ([]byte, error) {
+	doc, err := t.document()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON replaces the routes and the options of this trie with the ones encoded by the `MarshalJSON`.
+// The handlers are resolved by the `HandlerResolver` and the data are decoded by the `DataCodec` of this trie, i.e:
+// routes := muxie.NewTrieOf[*Policy]().DataCodec(policyCodec)
+// err := json.Unmarshal(b, routes)
+//
+// It should be called before the trie is searched, the options are not safe to change while searching.
+func (t *TrieOf[T]) UnmarshalJSON(b []byte) error {
+	var doc trieDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+
+	return t.loadDocument(&doc)
+}
+
+// the header of the binary encoding of a trie and its version.
+const (
+	binaryMagic   = "muxie"
+	binaryVersion = 1
+)
+
+// the options of the binary encoding, one bit each.
+const (
+	binaryCaseInsensitive byte = 1 << iota
+	binarySearchUnvisitedParams
+	binaryStrict
+	binaryCompress
+	binaryBacktracking
+)
+
+// MarshalBinary is the compact form of the `MarshalJSON`, with the same limits, see `UnmarshalBinary`.
+// The encoding is the header "muxie" and its version, a byte of option flags,
+// the number of the routes and their pattern, tag, handler name and data,
+// each one as a uvarint length followed by its bytes, the length of the data is one more, zero if there is no data.
+func (t *TrieOf[T]) MarshalBinary() ([]byte, error) {
+	doc, err := t.document()
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if doc.Options.CaseInsensitive {
+		flags |= binaryCaseInsensitive
+	}
+	if doc.Options.SearchUnvisitedParams {
+		flags |= binarySearchUnvisitedParams
+	}
+	if doc.Options.Strict {
+		flags |= binaryStrict
+	}
+	if doc.Options.Compress {
+		flags |= binaryCompress
+	}
+	if doc.Options.Backtracking {
+		flags |= binaryBacktracking
+	}
+
+	b := append([]byte(binaryMagic), binaryVersion, flags)
+	b = appendUvarint(b, uint64(len(doc.Routes)))
+	for _, r := range doc.Routes {
+		for _, s := range []string{r.Pattern, r.Tag, r.Handler} {
+			b = appendUvarint(b, uint64(len(s)))
+			b = append(b, s...)
+		}
+
+		if r.Data == nil {
+			b = appendUvarint(b, 0)
+			continue
+		}
+		b = appendUvarint(b, uint64(len(r.Data))+1)
+		b = append(b, r.Data...)
+	}
+
+	return b, nil
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// errInvalidBinary is returned by the `UnmarshalBinary` on malformed input.
+var errInvalidBinary = errors.New("muxie: invalid binary trie")
+
+// UnmarshalBinary is like the `UnmarshalJSON` but for the encoding of the `MarshalBinary`.
+func (t *TrieOf[T]) UnmarshalBinary(b []byte) error {
+	if !bytes.HasPrefix(b, []byte(binaryMagic)) || len(b) < len(binaryMagic)+2 {
+		return errInvalidBinary
+	}
+	b = b[len(binaryMagic):]
+
+	if b[0] != binaryVersion {
+		return fmt.Errorf("muxie: unsupported binary trie version %d", b[0])
+	}
+	flags := b[1]
+	b = b[2:]
+
+	doc := trieDocument{Options: trieDocumentOptions{
+		CaseInsensitive:       flags&binaryCaseInsensitive != 0,
+		SearchUnvisitedParams: flags&binarySearchUnvisitedParams != 0,
+		Strict:                flags&binaryStrict != 0,
+		Compress:              flags&binaryCompress != 0,
+		Backtracking:          flags&binaryBacktracking != 0,
+	}}
+
+	next := func() (uint64, bool) {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, false
+		}
+		b = b[n:]
+		return v, true
+	}
+
+	bytesOf := func(l uint64) ([]byte, bool) {
+		if l > uint64(len(b)) {
+			return nil, false
+		}
+		v := append([]byte(nil), b[:l]...)
+		b = b[l:]
+		return v, true
+	}
+
+	count, ok := next()
+	if !ok || count > uint64(len(b)) {
+		return errInvalidBinary
+	}
+
+	doc.Routes = make([]trieDocumentRoute, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var fields [3]string
+		for j := range fields {
+			l, ok := next()
+			if !ok {
+				return errInvalidBinary
+			}
+			s, ok := bytesOf(l)
+			if !ok {
+				return errInvalidBinary
+			}
+			fields[j] = string(s)
+		}
+
+		r := trieDocumentRoute{Pattern: fields[0], Tag: fields[1], Handler: fields[2]}
+		l, ok := next()
+		if !ok {
+			return errInvalidBinary
+		}
+		if l > 0 {
+			if r.Data, ok = bytesOf(l - 1); !ok {
+				return errInvalidBinary
+			}
+		}
+
+		doc.Routes = append(doc.Routes, r)
+	}
+
+	if len(b) > 0 {
+		return errInvalidBinary
+	}
+
+	return t.loadDocument(&doc)
+}
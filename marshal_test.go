@@ -0,0 +1,279 @@
+package muxie
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testPolicy struct {
+	Name  string   `json:"name"`
+	Rules []string `json:"rules"`
+}
+
+// testPolicyCodec encodes a policy as "name:rule1,rule2", which is not JSON.
+type testPolicyCodec struct{}
+
+func (testPolicyCodec) EncodeData(p *testPolicy) ([]byte, error) {
+	return []byte(p.Name + ":" + strings.Join(p.Rules, ",")), nil
+}
+
+func (testPolicyCodec) DecodeData(b []byte) (*testPolicy, error) {
+	name, rules, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid policy %q", b)
+	}
+
+	return &testPolicy{Name: name, Rules: strings.Split(rules, ",")}, nil
+}
+
+func testTrieRoutes[T any](tree *TrieOf[T]) (routes []string) {
+	tree.Walk(func(n *NodeOf[T]) error {
+		routes = append(routes, fmt.Sprintf("%s %s %v %v", n.key, n.Tag, n.Handler != nil, n.Data))
+		return nil
+	})
+
+	return
+}
+
+func TestTrieMarshal(t *testing.T) {
+	newTrie := func() *TrieOf[*testPolicy] {
+		return NewTrieOfWithOptions[*testPolicy](TrieOptions{CaseInsensitive: true, SearchUnvisitedParams: true, Compress: true})
+	}
+
+	tree := newTrie()
+	tree.Insert("/orgs/:org/*path", WithTagOf[*testPolicy]("org"), WithDataOf(&testPolicy{Name: "org", Rules: []string{"audit"}}))
+	tree.Insert("/orgs/:org/users/:id<int>", WithDataOf(&testPolicy{Name: "user", Rules: []string{"audit", "pii"}}))
+	tree.Insert("/api/v1/Settings/billing")
+	tree.Insert("/docs[/:lang]", WithTagOf[*testPolicy]("docs"))
+	tree.Insert("/files/:name.:ext", func(n *NodeOf[*testPolicy]) { n.Handler = http.NotFoundHandler() })
+
+	handlers := map[string]http.Handler{
+		"/files/:name.:ext": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("files")) }),
+	}
+	resolver := func(name string) (http.Handler, error) {
+		if h, ok := handlers[name]; ok {
+			return h, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	for _, codec := range []DataCodecOf[*testPolicy]{nil, testPolicyCodec{}} {
+		tree.DataCodec(codec)
+
+		jsonData, err := json.Marshal(tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		binaryData, err := tree.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fromJSON := newTrie()
+		if err = fromJSON.DataCodec(codec).HandlerResolver(resolver).UnmarshalJSON(jsonData); err != nil {
+			t.Fatalf("%T: %v", codec, err)
+		}
+		fromBinary := NewTrieOf[*testPolicy]()
+		if err = fromBinary.DataCodec(codec).HandlerResolver(resolver).UnmarshalBinary(binaryData); err != nil {
+			t.Fatalf("%T: %v", codec, err)
+		}
+
+		for _, loaded := range []*TrieOf[*testPolicy]{fromJSON, fromBinary} {
+			if expected, got := tree.options(), loaded.options(); expected != got {
+				t.Fatalf("%T: expected options: %#v but got: %#v", codec, expected, got)
+			}
+
+			if expected, got := len(testTrieRoutes(tree)), len(testTrieRoutes(loaded)); expected != got {
+				t.Fatalf("%T: expected %d routes but got: %d", codec, expected, got)
+			}
+
+			params := new(Writer)
+			n := loaded.Search("/ORGS/acme/users/42", params)
+			if n == nil || n.Data == nil || n.Data.Name != "user" || !reflect.DeepEqual(n.Data.Rules, []string{"audit", "pii"}) {
+				t.Fatalf("%T: expected the user policy but got: %v", codec, n)
+			}
+			if expected, got := []ParamEntry{{"org", "acme"}, {"id", "42"}}, params.GetAll(); !reflect.DeepEqual(expected, got) {
+				t.Fatalf("%T: expected params: %v but got: %v", codec, expected, got)
+			}
+
+			if n = loaded.Search("/docs/en", new(Writer)); n == nil || n.Tag != "docs" {
+				t.Fatalf("%T: expected the docs route but got: %v", codec, n)
+			}
+
+			if n = loaded.Search("/api/v1/settings/billing", new(Writer)); n == nil || n.Data != nil {
+				t.Fatalf("%T: expected the settings route without data but got: %v", codec, n)
+			}
+
+			n = loaded.Search("/files/a.txt", new(Writer))
+			if n == nil || n.Handler == nil {
+				t.Fatalf("%T: expected the files route with a handler but got: %v", codec, n)
+			}
+			rec := httptest.NewRecorder()
+			n.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/a.txt", nil))
+			if rec.Body.String() != "files" {
+				t.Fatalf("%T: expected the resolved handler but got: %q", codec, rec.Body.String())
+			}
+
+			// marshaling the loaded trie gives the same result.
+			again, err := loaded.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(binaryData) {
+				t.Fatalf("%T: expected the same binary encoding after load", codec)
+			}
+		}
+	}
+}
+
+func TestTrieMarshalJSON(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/users/:id", WithTag("user"), WithData(map[string]interface{}{"limit": 10}))
+
+	b, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"options":{},"routes":[{"pattern":"/users/:id","tag":"user","data":{"limit":10}}]}`
+	if string(b) != expected {
+		t.Fatalf("expected: %s but got: %s", expected, b)
+	}
+
+	// the zero value can be loaded too.
+	var loaded Trie
+	if err = json.Unmarshal(b, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	n := loaded.Search("/users/42", new(Writer))
+	if n == nil || !reflect.DeepEqual(n.Data, map[string]interface{}{"limit": float64(10)}) {
+		t.Fatalf("expected the user route with its data but got: %v", n)
+	}
+}
+
+func TestMuxRoutesMarshal(t *testing.T) {
+	write := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(body)) }
+	}
+
+	mux := NewMux()
+	mux.Handle("/users/:id", write("user"), WithTag("user"))
+	mux.Handle("/files/*path", write("files"))
+
+	// the name of a handler is the tag of its route, if any, see `HandlerResolver`.
+	handlers := map[string]http.Handler{"user": write("user"), "/files/*path": write("files")}
+	resolver := func(name string) (http.Handler, error) {
+		if h, ok := handlers[name]; ok {
+			return h, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	jsonData, err := mux.Routes.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryData, err := mux.Routes.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromJSON, fromBinary := NewMux(), NewMux()
+	if err = fromJSON.Routes.HandlerResolver(resolver).UnmarshalJSON(jsonData); err != nil {
+		t.Fatal(err)
+	}
+	if err = fromBinary.Routes.HandlerResolver(resolver).UnmarshalBinary(binaryData); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, loaded := range []*Mux{fromJSON, fromBinary} {
+		testHandler(t, loaded, http.MethodGet, "/users/42").statusCode(http.StatusOK).bodyEq("user")
+		testHandler(t, loaded, http.MethodGet, "/files/a/b.txt").statusCode(http.StatusOK).bodyEq("files")
+		if got, err := loaded.URL("user", "id", "42"); err != nil || got != "/users/42" {
+			t.Fatalf("expected the tag of the user route but got: %s (%v)", got, err)
+		}
+	}
+
+	// the handlers per method and the conditional handlers are not serialized, the routes which have them are errors.
+	methods := NewMux()
+	methods.Handle("/users/:id", write("user"))
+	methods.HandleMethod(http.MethodGet, "/items/:id", write("get item"))
+	methods.HandleMethod(http.MethodDelete, "/items/:id", write("delete item"))
+
+	matched := NewMux()
+	matched.Handle("/users/:id", write("user"))
+	matched.HandleWhen("/admin", Header("X-Admin", "true"), write("admin"))
+
+	wrapped := NewMux()
+	wrapped.Use(func(next http.Handler) http.Handler { return next })
+	wrapped.Handle("/users/:id", write("user"))
+
+	for name, mux := range map[string]*Mux{"HandleMethod": methods, "HandleWhen": matched, "Use": wrapped} {
+		if _, err = mux.Routes.MarshalJSON(); err == nil {
+			t.Fatalf("%s: expected a JSON error", name)
+		}
+		if _, err = mux.Routes.MarshalBinary(); err == nil {
+			t.Fatalf("%s: expected a binary error", name)
+		}
+	}
+}
+
+func TestTrieUnmarshalErrors(t *testing.T) {
+	withHandler := NewTrieOf[int]()
+	withHandler.Insert("/", func(n *NodeOf[int]) { n.Handler = http.NotFoundHandler() })
+	handlerData, err := withHandler.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := NewTrieOf[int]()
+	valid.Insert("/limits/:tier", WithDataOf(10))
+	validData, _ := valid.MarshalBinary()
+
+	tests := []struct {
+		name string
+		load func(tree *TrieOf[int]) error
+	}{
+		{"no resolver", func(tree *TrieOf[int]) error { return tree.UnmarshalBinary(handlerData) }},
+		{"resolver error", func(tree *TrieOf[int]) error {
+			return tree.HandlerResolver(func(name string) (http.Handler, error) {
+				return nil, errors.New("unknown handler " + strconv.Quote(name))
+			}).UnmarshalBinary(handlerData)
+		}},
+		{"truncated", func(tree *TrieOf[int]) error { return tree.UnmarshalBinary(validData[:len(validData)-1]) }},
+		{"trailing", func(tree *TrieOf[int]) error {
+			return tree.UnmarshalBinary(append(validData[:len(validData):len(validData)], 0))
+		}},
+		{"header", func(tree *TrieOf[int]) error { return tree.UnmarshalBinary([]byte("router")) }},
+		{"version", func(tree *TrieOf[int]) error { return tree.UnmarshalBinary([]byte("muxie\x09\x00\x00")) }},
+		{"invalid pattern", func(tree *TrieOf[int]) error {
+			return tree.UnmarshalJSON([]byte(`{"routes":[{"pattern":"/users/:id<unknown>"}]}`))
+		}},
+		{"empty pattern", func(tree *TrieOf[int]) error { return tree.UnmarshalJSON([]byte(`{"routes":[{"pattern":""}]}`)) }},
+		{"data", func(tree *TrieOf[int]) error {
+			return tree.UnmarshalJSON([]byte(`{"routes":[{"pattern":"/","data":"ten"}]}`))
+		}},
+	}
+
+	for _, tt := range tests {
+		tree := NewTrieOf[int]()
+		tree.Insert("/existing")
+
+		if err := tt.load(tree); err == nil {
+			t.Fatalf("%s: expected an error", tt.name)
+		}
+
+		// nothing changes on error.
+		if n := tree.Search("/existing", new(Writer)); n == nil {
+			t.Fatalf("%s: expected the trie to keep its routes", tt.name)
+		}
+	}
+}
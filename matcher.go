@@ -0,0 +1,348 @@
+package muxie
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// describedMatcher is a Matcher with a description of its condition, i.e `Header("X-Version", "v2")`,
+// the built-in matchers are described so the conditions of the routes can be listed, see `Route`.
+type describedMatcher struct {
+	MatcherFunc
+	description string
+}
+
+// String returns the description of the matcher.
+func (m describedMatcher) String() string {
+	return m.description
+}
+
+func describeMatcher(description string, fn MatcherFunc) Matcher {
+	return describedMatcher{MatcherFunc: fn, description: description}
+}
+
+// matcherString returns the description of the "matcher", its String if it implements the `fmt.Stringer`,
+// otherwise its type, i.e "muxie.MatcherFunc".
+func matcherString(matcher Matcher) string {
+	switch m := matcher.(type) {
+	case fmt.Stringer:
+		return m.String()
+	case Host:
+		return fmt.Sprintf("Host(%q)", string(m))
+	default:
+		return fmt.Sprintf("%T", matcher)
+	}
+}
+
+func matchersString(matchers []Matcher) string {
+	descriptions := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		descriptions = append(descriptions, matcherString(m))
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// quoteAll returns the quoted "values" separated by comma.
+func quoteAll(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// And returns a Matcher which passes when all the "matchers" pass, it passes if there are none.
+// The matchers are checked in order, it stops at the first one that does not pass.
+func And(matchers ...Matcher) Matcher {
+	return describeMatcher("And("+matchersString(matchers)+")", func(r *http.Request) bool {
+		for _, m := range matchers {
+			if !m.Match(r) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// Or returns a Matcher which passes when any of the "matchers" passes, it does not pass if there are none.
+// The matchers are checked in order, it stops at the first one that passes.
+func Or(matchers ...Matcher) Matcher {
+	return describeMatcher("Or("+matchersString(matchers)+")", func(r *http.Request) bool {
+		for _, m := range matchers {
+			if m.Match(r) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Not returns a Matcher which passes when the "matcher" does not.
+func Not(matcher Matcher) Matcher {
+	return describeMatcher("Not("+matcherString(matcher)+")", func(r *http.Request) bool {
+		return !matcher.Match(r)
+	})
+}
+
+// Method is a Matcher for the HTTP methods of the request, i.e `Method("GET", "HEAD")`,
+// a method can contain more than one separated by comma and/or space, i.e `Method("POST, PUT")`.
+func Method(methods ...string) Matcher {
+	var normalized []string
+	for _, method := range methods {
+		for _, m := range splitMethods(method) {
+			normalized = append(normalized, normalizeMethod(m))
+		}
+	}
+
+	return describeMatcher("Method("+quoteAll(normalized)+")", func(r *http.Request) bool {
+		for _, method := range normalized {
+			if r.Method == method {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// valueMatcher returns the function which checks a header, query or cookie value against the "value":
+// an empty "value" accepts any value, a "regex(expr)" one accepts the values that the "expr" matches as a whole,
+// any other is compared as it is. The "name" is the name of the Matcher for the panic message of an invalid expression.
+func valueMatcher(name, value string) func(string) bool {
+	switch {
+	case value == "":
+		return func(string) bool { return true }
+	case strings.HasPrefix(value, "regex(") && strings.HasSuffix(value, ")"):
+		expr, err := regexp.Compile("^(?:" + value[len("regex("):len(value)-1] + ")$")
+		if err != nil {
+			panic("muxie/" + name + ": " + err.Error())
+		}
+		return expr.MatchString
+	default:
+		return func(s string) bool { return s == value }
+	}
+}
+
+// Header is a Matcher for a request header, it passes if one of the header's values matches the "value".
+// An empty "value" requires the header to be present, a "regex(expr)" one is a regular expression
+// that should match the whole value, i.e `Header("X-Version", "regex(v[0-9]+)")`, any other is an exact value.
+// Panics on an invalid regular expression.
+func Header(name, value string) Matcher {
+	match := valueMatcher("Header", value)
+	name = http.CanonicalHeaderKey(name)
+
+	return describeMatcher(fmt.Sprintf("Header(%q, %q)", name, value), func(r *http.Request) bool {
+		for _, v := range r.Header[name] {
+			if match(v) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Query is a Matcher for a URL query parameter, it passes if one of the parameter's values matches the "value",
+// see `Header` for the syntax of the "value".
+func Query(key, value string) Matcher {
+	match := valueMatcher("Query", value)
+
+	return describeMatcher(fmt.Sprintf("Query(%q, %q)", key, value), func(r *http.Request) bool {
+		values, ok := r.URL.Query()[key]
+		if !ok {
+			return false
+		}
+
+		for _, v := range values {
+			if match(v) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Cookie is a Matcher for a request cookie, it passes if the cookie's value matches the "value",
+// see `Header` for the syntax of the "value".
+func Cookie(name, value string) Matcher {
+	match := valueMatcher("Cookie", value)
+
+	return describeMatcher(fmt.Sprintf("Cookie(%q, %q)", name, value), func(r *http.Request) bool {
+		c, err := r.Cookie(name)
+		return err == nil && match(c.Value)
+	})
+}
+
+// Scheme is a Matcher for the URL scheme of the request, i.e `Scheme("https")`.
+// The scheme of a server request is "https" if it was received over TLS, otherwise "http",
+// the "X-Forwarded-Proto" header is not trusted, use a `Header` matcher for it if there is a trusted proxy.
+func Scheme(schemes ...string) Matcher {
+	return describeMatcher("Scheme("+quoteAll(schemes)+")", func(r *http.Request) bool {
+		scheme := r.URL.Scheme
+		if scheme == "" {
+			scheme = "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+		}
+
+		for _, s := range schemes {
+			if strings.EqualFold(s, scheme) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// PathPrefix is a Matcher for the path of the request, it passes if the path is the "prefix"
+// or it continues with a path segment, i.e `PathPrefix("/api")` passes for the "/api" and the "/api/users"
+// but not for the "/apis". A "prefix" that ends with a slash is compared as it is.
+func PathPrefix(prefix string) Matcher {
+	return describeMatcher(fmt.Sprintf("PathPrefix(%q)", prefix), func(r *http.Request) bool {
+		path := r.URL.Path
+		if !strings.HasPrefix(path, prefix) {
+			return false
+		}
+
+		return len(path) == len(prefix) || prefix == "" || prefix[len(prefix)-1] == pathSepB || path[len(prefix)] == pathSepB
+	})
+}
+
+// RemoteCIDR is a Matcher for the network address of the client, i.e `RemoteCIDR("10.0.0.0/8", "::1/128")`,
+// it passes if the IP of the request's `RemoteAddr` is in one of the "cidrs".
+// The "X-Forwarded-For" header is not taken into account.
+// Panics on an invalid CIDR.
+func RemoteCIDR(cidrs ...string) Matcher {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic("muxie/RemoteCIDR: " + err.Error())
+		}
+		networks = append(networks, network)
+	}
+
+	return describeMatcher("RemoteCIDR("+quoteAll(cidrs)+")", func(r *http.Request) bool {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return false
+		}
+
+		for _, network := range networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// ContentType is a Matcher for the media type of the request body, i.e `ContentType("application/json")`,
+// its parameters like the charset are ignored. A type can be a range, i.e "text/*".
+func ContentType(types ...string) Matcher {
+	return describeMatcher("ContentType("+quoteAll(types)+")", func(r *http.Request) bool {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+
+		for _, t := range types {
+			if matchMediaRange(strings.ToLower(t), mediaType) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Accept is a Matcher for the media types that the client accepts, i.e `Accept("application/json")`,
+// it passes if one of the "types" is acceptable by the "Accept" header, ranges like "*/*" and "text/*"
+// and a zero quality are taken into account. A request without an "Accept" header accepts any type.
+func Accept(types ...string) Matcher {
+	return describeMatcher("Accept("+quoteAll(types)+")", func(r *http.Request) bool {
+		accept := r.Header.Values("Accept")
+		if len(accept) == 0 {
+			return len(types) > 0
+		}
+
+		for _, t := range types {
+			if acceptsMediaType(accept, strings.ToLower(t)) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// acceptsMediaType reports whether the "mediaType" is acceptable by the values of an "Accept" header,
+// the most specific media range that matches it decides, a zero quality refuses it.
+func acceptsMediaType(accept []string, mediaType string) bool {
+	var (
+		found       bool
+		specificity = -1
+		quality     float64
+	)
+
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mediaRange, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || !matchMediaRange(mediaRange, mediaType) {
+				continue
+			}
+
+			s := 2
+			if mediaRange == "*/*" {
+				s = 0
+			} else if strings.HasSuffix(mediaRange, "/*") {
+				s = 1
+			}
+			if s <= specificity {
+				continue
+			}
+
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+
+			found, specificity, quality = true, s, q
+		}
+	}
+
+	return found && quality > 0
+}
+
+// matchMediaRange reports whether the "mediaType" is in the "mediaRange", i.e "text/html" in "text/*".
+func matchMediaRange(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediaType, mediaRange[:len(mediaRange)-1])
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package muxie
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	newRequest := func(method, target string, headers ...string) *http.Request {
+		req := httptest.NewRequest(method, target, nil)
+		for i := 1; i < len(headers); i += 2 {
+			req.Header.Add(headers[i-1], headers[i])
+		}
+		return req
+	}
+
+	tlsRequest := newRequest(http.MethodGet, "/")
+	tlsRequest.TLS = new(tls.ConnectionState)
+
+	remoteRequest := newRequest(http.MethodGet, "/")
+	remoteRequest.RemoteAddr = "10.1.2.3:4567"
+
+	tests := []struct {
+		name     string
+		matcher  Matcher
+		req      *http.Request
+		expected bool
+	}{
+		{"and none", And(), newRequest(http.MethodGet, "/"), true},
+		{"and", And(Method("GET"), PathPrefix("/api")), newRequest(http.MethodGet, "/api/users"), true},
+		{"and fails", And(Method("GET"), PathPrefix("/api")), newRequest(http.MethodPost, "/api/users"), false},
+		{"or none", Or(), newRequest(http.MethodGet, "/"), false},
+		{"or", Or(Method("POST"), Method("get")), newRequest(http.MethodGet, "/"), true},
+		{"not", Not(Method("GET")), newRequest(http.MethodGet, "/"), false},
+		{"method list", Method("post, put"), newRequest(http.MethodPut, "/"), true},
+		{"header present", Header("x-version", ""), newRequest(http.MethodGet, "/", "X-Version", "v1"), true},
+		{"header missing", Header("X-Version", ""), newRequest(http.MethodGet, "/"), false},
+		{"header exact", Header("X-Version", "v1"), newRequest(http.MethodGet, "/", "X-Version", "v10"), false},
+		{"header regex", Header("X-Version", "regex(v[0-9]+)"), newRequest(http.MethodGet, "/", "X-Version", "v10"), true},
+		{"header regex whole value", Header("X-Version", "regex(v[0-9])"), newRequest(http.MethodGet, "/", "X-Version", "v10"), false},
+		{"header any value", Header("X-Version", "v2"), newRequest(http.MethodGet, "/", "X-Version", "v1", "X-Version", "v2"), true},
+		{"query", Query("debug", "1"), newRequest(http.MethodGet, "/?debug=1"), true},
+		{"query empty value", Query("debug", ""), newRequest(http.MethodGet, "/?debug"), true},
+		{"query missing", Query("debug", ""), newRequest(http.MethodGet, "/"), false},
+		{"cookie", Cookie("session", "regex([a-f0-9]+)"), newRequest(http.MethodGet, "/", "Cookie", "session=abc123"), true},
+		{"cookie missing", Cookie("session", ""), newRequest(http.MethodGet, "/"), false},
+		{"scheme http", Scheme("https"), newRequest(http.MethodGet, "/"), false},
+		{"scheme tls", Scheme("HTTPS"), tlsRequest, true},
+		{"path prefix segment", PathPrefix("/api"), newRequest(http.MethodGet, "/apis"), false},
+		{"path prefix exact", PathPrefix("/api"), newRequest(http.MethodGet, "/api"), true},
+		{"path prefix slash", PathPrefix("/api/"), newRequest(http.MethodGet, "/api/v1"), true},
+		{"remote cidr", RemoteCIDR("192.168.0.0/16", "10.0.0.0/8"), remoteRequest, true},
+		{"remote cidr outside", RemoteCIDR("192.168.0.0/16"), remoteRequest, false},
+		{"content type", ContentType("application/json"), newRequest(http.MethodPost, "/", "Content-Type", "Application/JSON; charset=utf-8"), true},
+		{"content type range", ContentType("text/*"), newRequest(http.MethodPost, "/", "Content-Type", "text/plain"), true},
+		{"content type missing", ContentType("application/json"), newRequest(http.MethodPost, "/"), false},
+		{"accept missing", Accept("application/json"), newRequest(http.MethodGet, "/"), true},
+		{"accept", Accept("application/json"), newRequest(http.MethodGet, "/", "Accept", "text/html, application/json;q=0.9"), true},
+		{"accept any", Accept("application/json"), newRequest(http.MethodGet, "/", "Accept", "*/*"), true},
+		{"accept other", Accept("application/json"), newRequest(http.MethodGet, "/", "Accept", "text/*"), false},
+		{"accept zero quality", Accept("application/json"), newRequest(http.MethodGet, "/", "Accept", "*/*, application/json;q=0"), false},
+		{"host empty", Host(""), newRequest(http.MethodGet, "/"), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.matcher.Match(tt.req); got != tt.expected {
+			t.Fatalf("%s: expected: %v but got: %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestMatchersPanic(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"header":      func() { Header("X-Version", "regex([)") },
+		"remote cidr": func() { RemoteCIDR("10.0.0.0") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestMuxHandleRequestMatchers(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/*path", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("any"))
+	})
+	mux.HandleRequest(And(PathPrefix("/api"), Header("X-Version", "v2")), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v2"))
+	}))
+
+	testHandler(t, mux, http.MethodGet, "/api/users").statusCode(http.StatusOK).bodyEq("any")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("X-Version", "v2")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if got := rec.Body.String(); got != "v2" {
+		t.Fatalf("expected the request handler to serve but got: %s", got)
+	}
+}
@@ -2,6 +2,7 @@ package muxie
 
 import (
 	"net/http"
+	"sort"
 	"strings"
 )
 
@@ -46,7 +47,9 @@ var NoContentHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter,
 //
 // Look `Handle` and `HandleFunc`.
 type MethodHandler struct {
-	// origin *Mux
+	// the Mux which registered this MethodHandler, if any,
+	// its `MethodNotAllowed` handler is used instead of the default one.
+	origin *Mux
 
 	handlers          map[string]http.Handler // method:handler
 	methodsAllowedStr string
@@ -60,9 +63,7 @@ type MethodHandler struct {
 //        ^ can accept many methods for the same handler
 //        ^ methods should be separated by comma, comma following by a space or just space
 func (m *MethodHandler) Handle(method string, handler http.Handler) *MethodHandler {
-	multiMethods := strings.FieldsFunc(method, func(c rune) bool {
-		return c == ',' || c == ' '
-	})
+	multiMethods := splitMethods(method)
 
 	if len(multiMethods) > 1 {
 		for _, method := range multiMethods {
@@ -115,9 +116,40 @@ func (m *MethodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	//
 	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Allow#Examples
 	w.Header().Set("Allow", m.methodsAllowedStr)
+	if m.origin != nil {
+		if methodNotAllowed := m.origin.errorHandlers.methodNotAllowed(r.URL.Path); methodNotAllowed != nil {
+			methodNotAllowed.ServeHTTP(w, r)
+			return
+		}
+	}
+
 	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 }
 
+// methods returns the registered HTTP methods, sorted.
+func (m *MethodHandler) methods() []string {
+	methods := make([]string, 0, len(m.handlers))
+	for method := range m.handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+func (m *MethodHandler) setOrigin(origin *Mux) {
+	if m.origin == nil {
+		m.origin = origin
+	}
+}
+
+// splitMethods splits methods separated by comma, comma following by a space or just space.
+func splitMethods(method string) []string {
+	return strings.FieldsFunc(method, func(c rune) bool {
+		return c == ',' || c == ' '
+	})
+}
+
 func normalizeMethod(method string) string {
 	return strings.ToUpper(strings.TrimSpace(method))
 }
@@ -49,3 +49,125 @@ func TestMethodHandler(t *testing.T) {
 	expect(t, http.MethodPut, srv.URL+"/user/42").statusCode(http.StatusMethodNotAllowed).
 		bodyEq("Method Not Allowed\n").headerEq("Allow", "GET, POST, DELETE")
 }
+
+func TestMethodHandlerMethodNotAllowed(t *testing.T) {
+	mux := NewMux()
+	mux.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Middleware", "1")
+			next.ServeHTTP(w, r)
+		})
+	})
+	mux.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "%s is not allowed, try: %s", r.Method, w.Header().Get("Allow"))
+	}))
+
+	mux.Handle("/user/:id", Methods().
+		HandleFunc(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "GET: User details by user ID: %s\n", GetParam(w, "id"))
+		}))
+
+	orphan := mux.Of("/orphan").Unlink()
+	orphan.Handle("/user/:id", Methods().
+		HandleFunc(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {}))
+
+	testHandler(t, mux, http.MethodGet, "/user/42").statusCode(http.StatusOK).
+		bodyEq("GET: User details by user ID: 42\n")
+	testHandler(t, mux, http.MethodPost, "/user/42").statusCode(http.StatusMethodNotAllowed).
+		bodyEq("POST is not allowed, try: GET").headerEq("Allow", "GET").headerEq("X-Middleware", "1")
+	testHandler(t, mux, http.MethodPost, "/orphan/user/42").statusCode(http.StatusMethodNotAllowed).
+		bodyEq("Method Not Allowed\n").headerEq("Allow", "GET")
+
+	// the middlewares run once, through the route.
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/42", nil))
+	if got := w.Header().Values("X-Middleware"); len(got) != 1 {
+		t.Fatalf("expected the middleware to run once but got: %v", got)
+	}
+}
+
+func TestMuxHandleMethod(t *testing.T) {
+	mux := NewMux()
+	mux.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	// registered independently.
+	mux.Get("/items/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "GET: item %s", GetParam(w, "id"))
+	})
+	mux.Of("/items").Delete("/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "DELETE: item %s", GetParam(w, "id"))
+	})
+	mux.HandleMethodFunc("POST, PUT", "/items/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s: item %s", r.Method, GetParam(w, "id"))
+	})
+
+	// a catch-all handler for the rest of the methods.
+	mux.Post("/any", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "POST: any")
+	})
+	mux.HandleFunc("/any", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s: any", r.Method)
+	})
+
+	testHandler(t, mux, http.MethodGet, "/items/42").statusCode(http.StatusOK).bodyEq("GET: item 42")
+	testHandler(t, mux, http.MethodHead, "/items/42").statusCode(http.StatusOK)
+	testHandler(t, mux, http.MethodDelete, "/items/42").statusCode(http.StatusOK).bodyEq("DELETE: item 42")
+	testHandler(t, mux, http.MethodPost, "/items/42").statusCode(http.StatusOK).bodyEq("POST: item 42")
+	testHandler(t, mux, http.MethodPut, "/items/42").statusCode(http.StatusOK).bodyEq("PUT: item 42")
+	testHandler(t, mux, http.MethodPatch, "/items/42").statusCode(http.StatusMethodNotAllowed).
+		bodyEq("Method Not Allowed\n").
+		headerEq("Allow", "DELETE, GET, POST, PUT, HEAD, OPTIONS").
+		headerEq("Access-Control-Allow-Origin", "*")
+	testHandler(t, mux, http.MethodOptions, "/items/42").statusCode(http.StatusNoContent).
+		headerEq("Allow", "DELETE, GET, POST, PUT, HEAD, OPTIONS").
+		headerEq("Access-Control-Allow-Origin", "*")
+	testHandler(t, mux, http.MethodGet, "/items").statusCode(http.StatusNotFound)
+
+	testHandler(t, mux, http.MethodPost, "/any").statusCode(http.StatusOK).bodyEq("POST: any")
+	testHandler(t, mux, http.MethodPatch, "/any").statusCode(http.StatusOK).bodyEq("PATCH: any")
+
+	mux.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		fmt.Fprintf(w, "try: %s", w.Header().Get("Allow"))
+	}))
+	testHandler(t, mux, http.MethodPatch, "/items/42").statusCode(http.StatusMethodNotAllowed).
+		bodyEq("try: DELETE, GET, POST, PUT, HEAD, OPTIONS")
+}
+
+func TestMuxHandleReregister(t *testing.T) {
+	text := func(s string) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s: %s", r.Method, s)
+		}
+	}
+
+	mux := NewMux()
+	mux.HandleFunc("/any", text("first"), WithTag("any"))
+	mux.Post("/any", text("first post"))
+	mux.Get("/any", text("get"))
+	// the last registration wins.
+	mux.HandleFunc("/any", text("second"))
+	mux.Post("/any", text("second post"))
+	mux.HandleFunc("/renamed", text("renamed"), WithTag("first"))
+	mux.HandleFunc("/renamed", text("renamed"), WithTag("second"))
+
+	testHandler(t, mux, http.MethodPatch, "/any").statusCode(http.StatusOK).bodyEq("PATCH: second")
+	testHandler(t, mux, http.MethodPost, "/any").statusCode(http.StatusOK).bodyEq("POST: second post")
+	// the handlers of the other methods and the tag are kept.
+	testHandler(t, mux, http.MethodGet, "/any").statusCode(http.StatusOK).bodyEq("GET: get")
+	if got, err := mux.URL("any"); err != nil || got != "/any" {
+		t.Fatalf("expected the tag to be kept but got: '%s' (%v)", got, err)
+	}
+	if got, err := mux.URL("second"); err != nil || got != "/renamed" {
+		t.Fatalf("expected the last tag but got: '%s' (%v)", got, err)
+	}
+	if _, err := mux.URL("first"); err == nil {
+		t.Fatalf("expected the replaced tag to not be found")
+	}
+}
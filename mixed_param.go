@@ -0,0 +1,295 @@
+package muxie
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MixedParamEscape is the character, as a string, which escapes a ":" that is part of the literal text of a path segment
+// with mixed parameters, i.e "/v1/:name\:cancel" matches "/v1/operation-1:cancel".
+const MixedParamEscape = `\`
+
+// mixedSegment is a path segment pattern which contains any mix of literal text and named parameters,
+// i.e ":name.:ext", "v:major.:minor", ":w x :h.png" or "@:user".
+//
+// The parameters are matched from left to right and each one takes the shortest non-empty value
+// that lets the rest of the segment match: ":name.:ext" matches "archive.tar.gz" as name="archive" and ext="tar.gz".
+// A parameter that ends the segment takes the rest of it. Two parameters can not be next to each other,
+// a literal text should separate them.
+type mixedSegment struct {
+	parts []mixedPart
+	// the length of the literal text, more specific segments are tried first.
+	literals int
+	params   int
+}
+
+// mixedPart is a literal text or a named parameter of a `mixedSegment`.
+type mixedPart struct {
+	literal string
+
+	// the parameter's name, empty for a literal part.
+	name string
+	// the constraint of the parameter as it is written inside the "<>", i.e "int", if any.
+	constraintKey string
+	constraint    ParamConstraint
+}
+
+func isParamNameStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isParamNameChar(c byte) bool {
+	return isParamNameStart(c) || c >= '0' && c <= '9'
+}
+
+// isMixedParam reports whether the path segment "s" of a pattern is a segment with mixed parameters.
+// A segment that starts with ":" is a named parameter unless it has a second parameter or an escaped ":",
+// the prefix (`img+:name`) and suffix (`name-:.txt`) parameters keep their syntax,
+// any other segment with a ":" followed by a parameter's name has mixed parameters.
+func isMixedParam(s string) bool {
+	if s == "" || s[0] == WildcardParamStart[0] {
+		return false
+	}
+
+	i := 0
+	if s[0] == ParamStart[0] {
+		// skip the first parameter.
+		i = 1
+		for i < len(s) && isParamNameChar(s[i]) {
+			i++
+		}
+		i = skipMixedConstraint(s, i)
+	} else if isPrefixParam(s) || isSuffixParam(s) {
+		return false
+	}
+
+	for ; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], MixedParamEscape+ParamStart):
+			return true
+		case s[i] == ParamStart[0] && i+1 < len(s) && isParamNameStart(s[i+1]):
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipMixedConstraint returns the index after the constraint of a parameter which starts at the "i", if any,
+// the "<>" of a constraint may contain parentheses, i.e "<regex(a<b>)>".
+func skipMixedConstraint(s string, i int) int {
+	if i >= len(s) || s[i] != ParamConstraintStart[0] {
+		return i
+	}
+
+	depth := 0
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ParamConstraintEnd[0]:
+			if depth <= 0 {
+				return j + 1
+			}
+		}
+	}
+
+	return len(s)
+}
+
+// parseMixedSegment parses a path segment with mixed parameters, see `isMixedParam`.
+// The literal text is lowercased if "caseInsensitive" is true.
+func parseMixedSegment(s string, caseInsensitive bool) (*mixedSegment, error) {
+	m := new(mixedSegment)
+
+	var literal strings.Builder
+	addLiteral := func() {
+		if literal.Len() == 0 {
+			return
+		}
+
+		text := literal.String()
+		if caseInsensitive {
+			text = strings.ToLower(text)
+		}
+		m.parts = append(m.parts, mixedPart{literal: text})
+		m.literals += len(text)
+		literal.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], MixedParamEscape+ParamStart) {
+			literal.WriteString(ParamStart)
+			i += len(MixedParamEscape + ParamStart)
+			continue
+		}
+
+		if s[i] != ParamStart[0] || i+1 >= len(s) || !isParamNameStart(s[i+1]) {
+			literal.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		addLiteral()
+		if len(m.parts) > 0 && m.parts[len(m.parts)-1].name != "" {
+			return nil, fmt.Errorf("parameters %q and %q should be separated by a literal text", m.parts[len(m.parts)-1].name, s[i:])
+		}
+
+		start := i + 1
+		i = start
+		for i < len(s) && isParamNameChar(s[i]) {
+			i++
+		}
+		end := skipMixedConstraint(s, i)
+
+		name, constraintKey, constraint, err := parseParamConstraint(s[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		m.parts = append(m.parts, mixedPart{name: name, constraintKey: constraintKey, constraint: constraint})
+		m.params++
+		i = end
+	}
+	addLiteral()
+
+	return m, nil
+}
+
+// key returns the key that the segment is stored under its parent node,
+// the literal text with the parameters as "{}", or "{int}" for a parameter with an "int" constraint,
+// and a ":" in front, so it can not be a static path segment's key, i.e ":{}.{}" for the ":name.:ext".
+func (m *mixedSegment) key() string {
+	var b strings.Builder
+	b.WriteString(ParamStart)
+	for _, part := range m.parts {
+		if part.name == "" {
+			b.WriteString(strings.Replace(part.literal, "{", "{{", -1))
+			continue
+		}
+
+		b.WriteByte('{')
+		b.WriteString(part.constraintKey)
+		b.WriteByte('}')
+	}
+
+	return b.String()
+}
+
+// names returns the names of the parameters, in order of appearance.
+func (m *mixedSegment) names() []string {
+	names := make([]string, 0, m.params)
+	for _, part := range m.parts {
+		if part.name != "" {
+			names = append(names, part.name)
+		}
+	}
+
+	return names
+}
+
+// match matches the path segment "s" and appends the values of the parameters to the "values",
+// "sc" is the "s" to compare the literal text against, it is lowercased on case-insensitive tries.
+// If it does not match then the "values" are returned as they were given.
+func (m *mixedSegment) match(s, sc string, values []string) ([]string, bool) {
+	n := len(values)
+	values, ok := matchMixedParts(m.parts, s, sc, values)
+	if !ok {
+		return values[:n], false
+	}
+
+	return values, true
+}
+
+func matchMixedParts(parts []mixedPart, s, sc string, values []string) ([]string, bool) {
+	if len(parts) == 0 {
+		return values, s == ""
+	}
+
+	part := parts[0]
+	if part.name == "" {
+		if !strings.HasPrefix(sc, part.literal) {
+			return values, false
+		}
+
+		return matchMixedParts(parts[1:], s[len(part.literal):], sc[len(part.literal):], values)
+	}
+
+	// the last part takes the rest of the segment.
+	if len(parts) == 1 {
+		if s == "" || (part.constraint != nil && !part.constraint(s)) {
+			return values, false
+		}
+
+		return append(values, s), true
+	}
+
+	// the next part is a literal, try its occurrences from the left, the value can not be empty.
+	literal := parts[1].literal
+	for i := 1; i < len(sc); i++ {
+		idx := strings.Index(sc[i:], literal)
+		if idx == -1 {
+			break
+		}
+		i += idx
+
+		if value := s[:i]; part.constraint == nil || part.constraint(value) {
+			n := len(values)
+			if matched, ok := matchMixedParts(parts[2:], s[i+len(literal):], sc[i+len(literal):], append(values, value)); ok {
+				return matched, true
+			}
+			values = values[:n]
+		}
+	}
+
+	return values, false
+}
+
+// addMixedParameter adds the "key" of a mixed parameters child to this node,
+// the keys are kept in order of specificity: the ones with the longest literal text first.
+func (n *NodeOf[T]) addMixedParameter(key string, m *mixedSegment) {
+	for _, k := range n.childMixedParameters {
+		if k == key {
+			return
+		}
+	}
+
+	i := len(n.childMixedParameters)
+	for j, k := range n.childMixedParameters {
+		if n.getChild(k).mixed.literals < m.literals {
+			i = j
+			break
+		}
+	}
+
+	n.childMixedParameters = append(n.childMixedParameters, "")
+	copy(n.childMixedParameters[i+1:], n.childMixedParameters[i:])
+	n.childMixedParameters[i] = key
+}
+
+// getMixedParamChild returns the first mixed parameters child that matches the path segment "s",
+// "sc" is the "s" to compare the literal text against. The values of its parameters are appended to the "values".
+func (n *NodeOf[T]) getMixedParamChild(s, sc string, values []string) (*NodeOf[T], []string) {
+	for _, key := range n.childMixedParameters {
+		child := n.getChild(key)
+		var ok bool
+		if values, ok = child.mixed.match(s, sc, values); ok {
+			return child, values
+		}
+	}
+
+	return nil, values
+}
+
+// appendParamValues appends the values of this parameter node for the path segment "s" to the "values",
+// one value for a single parameter, the ones of its parameters for mixed parameters.
+func (n *NodeOf[T]) appendParamValues(s, sc string, values []string) []string {
+	if n.mixed != nil {
+		values, _ = n.mixed.match(s, sc, values)
+		return values
+	}
+
+	return append(values, s)
+}
@@ -0,0 +1,188 @@
+package muxie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsMixedParam(t *testing.T) {
+	tests := []struct {
+		segment string
+		mixed   bool
+	}{
+		{":name.:ext", true},
+		{"v:major.:minor", true},
+		{":w x :h.png", true},
+		{"@:user", true},
+		{`:name\:cancel`, true},
+		{":id<int>.:ext", true},
+		{":name<regex(a:b)>", false},
+		{":name", false},
+		{":file.txt", false},
+		{"*path", false},
+		{"img+:name", false},
+		{"name-:.txt", false},
+		{"static", false},
+		{"a:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isMixedParam(tt.segment); got != tt.mixed {
+			t.Fatalf("%s: expected mixed: %v but got: %v", tt.segment, tt.mixed, got)
+		}
+	}
+}
+
+func TestTrieMixedParams(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/files/:name.:ext", WithTag("file"))
+	tree.Insert("/files/:name.min.:ext", WithTag("min_file"))
+	tree.Insert("/files/:name", WithTag("any_file"))
+	tree.Insert("/v:major.:minor/items", WithTag("versioned_items"))
+	tree.Insert("/img/:w x :h.png", WithTag("image"))
+	tree.Insert("/@:user", WithTag("user"))
+	tree.Insert("/sizes/:w<int>x:h<int>", WithTag("size"))
+	tree.Insert(`/ops/:name\:cancel`, WithTag("cancel"))
+
+	tests := []struct {
+		path   string
+		tag    string
+		params []ParamEntry
+	}{
+		{"/files/archive.tar.gz", "file", []ParamEntry{{"name", "archive"}, {"ext", "tar.gz"}}},
+		{"/files/app.min.js", "min_file", []ParamEntry{{"name", "app"}, {"ext", "js"}}},
+		{"/files/.profile", "any_file", []ParamEntry{{"name", ".profile"}}},
+		{"/files/readme.", "any_file", []ParamEntry{{"name", "readme."}}},
+		{"/files/readme", "any_file", []ParamEntry{{"name", "readme"}}},
+		{"/v1.2/items", "versioned_items", []ParamEntry{{"major", "1"}, {"minor", "2"}}},
+		{"/v1/items", "", nil},
+		{"/img/640 x 480.png", "image", []ParamEntry{{"w", "640"}, {"h", "480"}}},
+		{"/img/640x480.png", "", nil},
+		{"/@kataras", "user", []ParamEntry{{"user", "kataras"}}},
+		{"/@", "", nil},
+		{"/sizes/640x480", "size", []ParamEntry{{"w", "640"}, {"h", "480"}}},
+		{"/sizes/640xl", "", nil},
+		{"/sizes/0x10x20", "", nil},
+		{"/ops/operation-1:cancel", "cancel", []ParamEntry{{"name", "operation-1"}}},
+	}
+
+	for _, tt := range tests {
+		params := new(Writer)
+		n := tree.Search(tt.path, params)
+		if tt.tag == "" {
+			if n != nil {
+				t.Fatalf("%s: expected to not be found but found: %s", tt.path, n.String())
+			}
+			continue
+		}
+
+		if n == nil || n.Tag != tt.tag {
+			t.Fatalf("%s: expected to be found by: %s but got: %v", tt.path, tt.tag, n)
+		}
+
+		if got := params.GetAll(); !reflect.DeepEqual(tt.params, got) {
+			t.Fatalf("%s: expected params: %v but got: %v", tt.path, tt.params, got)
+		}
+	}
+
+	if e := tree.Explain("/files/app.min.js"); e.Steps[1].Rule != MatchMixedParam || e.Steps[1].Key != ":{}.min.{}" {
+		t.Fatalf("expected the mixed parameters to be explained but got:\n%s", e)
+	}
+
+	if !tree.Delete("/files/:name.min.:ext") {
+		t.Fatalf("expected the mixed parameters route to be deleted")
+	}
+	if n := tree.Search("/files/app.min.js", new(Writer)); n == nil || n.Tag != "file" {
+		t.Fatalf("expected the other mixed parameters route to be found after delete but got: %v", n)
+	}
+}
+
+func TestTrieMixedParamsCaseInsensitive(t *testing.T) {
+	tree := NewTrieWithOptions(TrieOptions{CaseInsensitive: true, Compress: true})
+	tree.Insert("/API/v:major.:minor/Items", WithTag("items"))
+
+	params := new(Writer)
+	if n := tree.Search("/api/V1.2/ITEMS", params); n == nil || n.Tag != "items" {
+		t.Fatalf("expected the route to be found but got: %v", n)
+	}
+
+	if expected, got := []ParamEntry{{"major", "1"}, {"minor", "2"}}, params.GetAll(); !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected params: %v but got: %v", expected, got)
+	}
+}
+
+func TestTrieMixedParamsUnvisited(t *testing.T) {
+	tree := NewTrie().SearchUnvisitedParams()
+	tree.Insert("/a/b.c/z", WithTag("static"))
+	tree.Insert("/a/:x.:y/d", WithTag("mixed"))
+
+	params := new(Writer)
+	if n := tree.Search("/a/b.c/d", params); n == nil || n.Tag != "mixed" {
+		t.Fatalf("expected the unvisited mixed parameters to be found but got: %v", n)
+	}
+
+	if expected, got := []ParamEntry{{"x", "b"}, {"y", "c"}}, params.GetAll(); !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected params: %v but got: %v", expected, got)
+	}
+}
+
+func TestTrieMixedParamsInvalid(t *testing.T) {
+	for _, pattern := range []string{"/files/:name:ext", "/files/:name.:ext<unknown>"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("%s: expected a panic", pattern)
+				}
+			}()
+
+			NewTrie().Insert(pattern)
+		}()
+	}
+}
+
+func TestTrieMixedParamsRoute(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/files/:name.:ext<alpha>", WithTag("file"))
+	tree.Insert("/docs/:name.:ext", WithTag("doc"))
+
+	route := tree.Search("/files/a.txt", new(Writer)).Route()
+	expectedParams := []RouteParam{
+		{Name: "name", Kind: MixedParam, Affix: ":name.:ext<alpha>"},
+		{Name: "ext", Kind: MixedParam, Constraint: "alpha", Affix: ":name.:ext<alpha>"},
+	}
+	if !reflect.DeepEqual(expectedParams, route.Params) {
+		t.Fatalf("expected params: %#v but got: %#v", expectedParams, route.Params)
+	}
+
+	path, parameters := openAPIPath(route)
+	if expected := "/files/{name}.{ext}"; path != expected {
+		t.Fatalf("expected OpenAPI path: %s but got: %s", expected, path)
+	}
+	if len(parameters) != 2 || parameters[1].Schema.Pattern != "^[a-zA-Z]+$" {
+		t.Fatalf("expected the OpenAPI parameters of the mixed parameters but got: %#v", parameters)
+	}
+
+	if got, err := tree.Reverse("file", map[string]string{"name": "my report", "ext": "txt"}); err != nil || got != "/files/my%20report.txt" {
+		t.Fatalf("expected the reversed path but got: %s (%v)", got, err)
+	}
+
+	// the constraint lets the name contain a dot, without it the search would not split it back the same.
+	if got, err := tree.Reverse("file", map[string]string{"name": "a.b", "ext": "txt"}); err != nil || got != "/files/a.b.txt" {
+		t.Fatalf("expected the reversed path but got: %s (%v)", got, err)
+	}
+	if _, err := tree.Reverse("doc", map[string]string{"name": "a.b", "ext": "txt"}); err == nil {
+		t.Fatalf("expected an error for a value that does not match back")
+	}
+	if _, err := tree.Reverse("file", map[string]string{"name": "a", "ext": "1"}); err == nil {
+		t.Fatalf("expected an error for a value that does not pass the constraint")
+	}
+
+	if err := NewTrie().Strict().TryInsert("/files/:name.:ext"); err != nil {
+		t.Fatal(err)
+	}
+	strict := NewTrie()
+	strict.Insert("/files/:name.:ext")
+	if err := strict.TryInsert("/files/:base.:ext/info"); err == nil {
+		t.Fatalf("expected a warning for a differently named mixed parameter")
+	}
+}
@@ -1,9 +1,12 @@
 package muxie
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // Mux is an HTTP request multiplexer.
@@ -19,6 +22,9 @@ import (
 // the pattern "/*myparam" matches all paths not matched by other registered
 // patterns, but not the URL with Path == "/", for that you would need the pattern "/".
 //
+// Routes, request handlers and middlewares can be registered and removed
+// while the Mux is serving requests, see `Batch` too.
+//
 // See `NewMux`.
 type Mux struct {
 	// PathCorrection removes leading slashes from the request path.
@@ -28,14 +34,28 @@ type Mux struct {
 	// it will execute the handlers chain without redirection.
 	// Defaults to false.
 	PathCorrectionNoRedirect bool
-	Routes                   *Trie
+	// ParamsMode describes where the path parameters are stored, besides the handler's response writer.
+	// Set it to `ParamsInContext` and/or `ParamsInPathValue` when middlewares replace the response writer
+	// without unwrapping to the original one, the parameters are then available through the `RequestParam`.
+	// Defaults to `ParamsInWriter`.
+	ParamsMode ParamsMode
+	Routes     *Trie
 
 	paramsPool *sync.Pool
 
 	// per mux
-	root            string
-	requestHandlers []RequestHandler
+	root string
+	// mu protects the per mux fields from concurrent registrations.
+	mu sync.Mutex
+	// requestHandlers holds a []RequestHandler which is replaced on each registration,
+	// so the ServeHTTP can read it without locking.
+	requestHandlers atomic.Value
 	beginHandlers   []Wrapper
+	// shared between the Mux and its sub muxes.
+	errorHandlers *errorHandlers
+	hosts         *hostRoutes
+	// set to 1 by the first `HandleStd` route which redirects the path without its trailing slash.
+	slashRedirects *uint32
 }
 
 // NewMux returns a new HTTP multiplexer which uses a fast, if not the fastest
@@ -48,7 +68,10 @@ func NewMux() *Mux {
 				return &Writer{}
 			},
 		},
-		root: "",
+		root:           "",
+		errorHandlers:  newErrorHandlers(),
+		hosts:          new(hostRoutes),
+		slashRedirects: new(uint32),
 	}
 }
 
@@ -64,7 +87,15 @@ func NewMux() *Mux {
 // middlewares then you have to use the `muxie.Pre` to declare
 // the shared middlewares and register them via the `Mux#Use` function.
 func (m *Mux) AddRequestHandler(requestHandler RequestHandler) {
-	m.requestHandlers = append(m.requestHandlers, requestHandler)
+	m.mu.Lock()
+	requestHandlers := m.getRequestHandlers()
+	m.requestHandlers.Store(append(requestHandlers[:len(requestHandlers):len(requestHandlers)], requestHandler))
+	m.mu.Unlock()
+}
+
+func (m *Mux) getRequestHandlers() []RequestHandler {
+	requestHandlers, _ := m.requestHandlers.Load().([]RequestHandler)
+	return requestHandlers
 }
 
 // HandleRequest adds a matcher and a (conditional) handler to be executed when "matcher" passed.
@@ -90,7 +121,16 @@ func (m *Mux) HandleRequest(matcher Matcher, handler http.Handler) {
 // Functionality of `Use` is pretty self-explained but new gophers should
 // take a look of the examples for further details.
 func (m *Mux) Use(middlewares ...Wrapper) {
-	m.beginHandlers = append(m.beginHandlers, middlewares...)
+	m.mu.Lock()
+	m.beginHandlers = append(m.beginHandlers[:len(m.beginHandlers):len(m.beginHandlers)], middlewares...)
+	m.mu.Unlock()
+}
+
+func (m *Mux) getBeginHandlers() []Wrapper {
+	m.mu.Lock()
+	beginHandlers := m.beginHandlers
+	m.mu.Unlock()
+	return beginHandlers
 }
 
 type (
@@ -132,21 +172,215 @@ func Pre(middleware ...Wrapper) Wrappers {
 	return Wrappers(middleware)
 }
 
+// NotFound sets the handler which is executed when no route matches a request path
+// that belongs to this Mux, sub muxes inherit it unless they set their own or they are unlinked.
+// The handler runs through the middlewares registered by `Use` before this call.
+// Defaults to the `http.NotFound`.
+//
+// Note that a wildcard, i.e "/*path" is still preferred over the not found handler.
+func (m *Mux) NotFound(handler http.Handler) {
+	wrapped := Pre(m.getBeginHandlers()...).For(handler)
+	m.errorHandlers.update(m.root, func(entry *errorHandlersEntry) {
+		entry.notFound = wrapped
+	})
+}
+
+// MethodNotAllowed sets the handler which is executed when a route of this Mux matches the request path
+// but not its HTTP method, sub muxes inherit it unless they set their own or they are unlinked.
+// The "Allow" response header is set before the handler is executed.
+// The handler runs through the middlewares of the matched route.
+// Defaults to a handler which sends the 405 status code and its text.
+//
+// See `Methods` too.
+func (m *Mux) MethodNotAllowed(handler http.Handler) {
+	m.errorHandlers.update(m.root, func(entry *errorHandlersEntry) {
+		entry.methodNotAllowed = handler
+	})
+}
+
 // Handle registers a route handler for a path pattern.
-func (m *Mux) Handle(pattern string, handler http.Handler) {
-	m.Routes.Insert(m.root+pattern,
-		WithHandler(
-			Pre(m.beginHandlers...).For(handler)))
+// The optional "options" can be used to alter the route's node,
+// i.e `WithTag("user")` gives a name to the route for the `URL` method.
+//
+// The pattern may start with a host pattern, i.e ":tenant.app.com/projects/:id",
+// its labels can be named parameters, ":tenant" or "{tenant}", and its first label can be a wildcard
+// which matches one or more labels, i.e "*.api.app.com". The host parameters are available
+// through the `GetParam` like the path ones. A host pattern without a port matches any port.
+// The routes of the host patterns which match the request's host are searched first,
+// from the most specific host pattern to the least one, then the routes without a host.
+//
+// A pattern that does not start with a slash is a host pattern followed by its path,
+// the host pattern should have a dot or a port, i.e "app.com/about" or "localhost:8080/about",
+// a single label host is written with a trailing dot, i.e "localhost./about".
+// Other patterns without a slash, i.e "users/:id", are ambiguous and they panic.
+func (m *Mux) Handle(pattern string, handler http.Handler, options ...InsertOption) {
+	methodHandler, ok := handler.(*MethodHandler)
+	if ok {
+		methodHandler.setOrigin(m)
+	}
+
+	beginHandlers := m.getBeginHandlers()
+	m.withRoutes("Handle", pattern, true, func(routes *Trie, path string) {
+		routes.Insert(path,
+			append([]InsertOption{withHandler(
+				Pre(beginHandlers...).For(handler), methodHandler), withMiddlewares(len(beginHandlers))}, options...)...)
+	})
 }
 
 // HandleFunc registers a route handler function for a path pattern.
-func (m *Mux) HandleFunc(pattern string, handlerFunc func(http.ResponseWriter, *http.Request)) {
-	m.Handle(pattern, http.HandlerFunc(handlerFunc))
+func (m *Mux) HandleFunc(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.Handle(pattern, http.HandlerFunc(handlerFunc), options...)
+}
+
+// HandleMethod registers a route handler for a path pattern and specific HTTP method(s),
+// methods can be separated by comma, comma following by a space or just space, i.e "POST, PUT".
+// Handlers of different methods can be registered independently for the same path pattern.
+//
+// When the path matches but the method does not then the Mux answers with the `MethodNotAllowed` handler
+// and the "Allow" header. HEAD requests are served by the GET handler and
+// OPTIONS requests are answered with the allowed methods, unless there are handlers for them.
+// A handler registered through `Handle` for the same path pattern serves the rest of the methods.
+//
+// Usage:
+// mux.HandleMethod(http.MethodGet, "/items/:id", getItemHandler)
+// mux.HandleMethod(http.MethodDelete, "/items/:id", deleteItemHandler)
+func (m *Mux) HandleMethod(method, pattern string, handler http.Handler, options ...InsertOption) {
+	beginHandlers := m.getBeginHandlers()
+	wrappers := Pre(beginHandlers...)
+
+	methodOptions := []InsertOption{withMethodFallback(wrappers.ForFunc(m.serveMethodFallback)), withMiddlewares(len(beginHandlers))}
+	for _, method := range splitMethods(method) {
+		methodOptions = append(methodOptions, WithMethodHandler(method, wrappers.For(handler)))
+	}
+
+	m.withRoutes("HandleMethod", pattern, true, func(routes *Trie, path string) {
+		routes.Insert(path, append(methodOptions, options...)...)
+	})
+}
+
+// HandleMethodFunc registers a route handler function for a path pattern and specific HTTP method(s).
+//
+// See `HandleMethod`.
+func (m *Mux) HandleMethodFunc(method, pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleMethod(method, pattern, http.HandlerFunc(handlerFunc), options...)
+}
+
+// Get registers a route handler function for the GET (and HEAD) HTTP method.
+func (m *Mux) Get(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleMethodFunc(http.MethodGet, pattern, handlerFunc, options...)
+}
+
+// Post registers a route handler function for the POST HTTP method.
+func (m *Mux) Post(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleMethodFunc(http.MethodPost, pattern, handlerFunc, options...)
+}
+
+// Put registers a route handler function for the PUT HTTP method.
+func (m *Mux) Put(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleMethodFunc(http.MethodPut, pattern, handlerFunc, options...)
+}
+
+// Patch registers a route handler function for the PATCH HTTP method.
+func (m *Mux) Patch(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleMethodFunc(http.MethodPatch, pattern, handlerFunc, options...)
+}
+
+// Delete registers a route handler function for the DELETE HTTP method.
+func (m *Mux) Delete(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleMethodFunc(http.MethodDelete, pattern, handlerFunc, options...)
+}
+
+// serveMethodFallback answers the OPTIONS and the not allowed methods
+// of the routes registered through `HandleMethod`, the "Allow" header is already set.
+func (m *Mux) serveMethodFallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if methodNotAllowed := m.errorHandlers.methodNotAllowed(r.URL.Path); methodNotAllowed != nil {
+		methodNotAllowed.ServeHTTP(w, r)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// URL returns the path of a route based on its name(see `WithTag`)
+// and its parameters as key-value pairs, i.e:
+// mux.HandleFunc("/users/:id", userHandler, muxie.WithTag("user"))
+// mux.URL("user", "id", "42") returns "/users/42".
+//
+// The route of a host pattern is searched if there is no route with that name without a host,
+// the path is returned without the host and its host parameters, if given, are ignored.
+//
+// See `Trie#Reverse` too.
+func (m *Mux) URL(name string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("muxie: route %q: parameters should be key-value pairs", name)
+	}
+
+	paramsMap := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		paramsMap[params[i]] = params[i+1]
+	}
+
+	if m.Routes.load().getTagged(name) != nil {
+		return m.Routes.Reverse(name, paramsMap)
+	}
+
+	var routes *Trie
+	m.hosts.each(func(e *hostEntry) {
+		if routes == nil && e.routes.load().getTagged(name) != nil {
+			routes = e.routes
+			for _, hostParam := range e.params {
+				delete(paramsMap, hostParam)
+			}
+		}
+	})
+	if routes == nil {
+		routes = m.Routes
+	}
+
+	return routes.Reverse(name, paramsMap)
+}
+
+// Remove removes the route handler of a path pattern,
+// it reports whether the pattern was registered.
+//
+// See `Trie#Delete` too.
+func (m *Mux) Remove(pattern string) (removed bool) {
+	m.withRoutes("Remove", pattern, false, func(routes *Trie, path string) {
+		removed = routes.Delete(path)
+	})
+
+	return
+}
+
+// Batch calls "fn" with a SubMux of this Mux, the routes that are registered or removed
+// through it (and its `Of`) are published at once, when the "fn" returns.
+// Requests that are served in the meantime see only the previous routes.
+// Useful to reload many routes of a Mux which is already serving requests.
+//
+// See `Trie#Update` too.
+func (m *Mux) Batch(fn func(SubMux)) {
+	// the routes of the host patterns are registered to copies of their tables,
+	// which are published along with the routes without a host.
+	m.hosts.mu.Lock()
+	defer m.hosts.mu.Unlock()
+	hosts := m.hosts.stage()
+
+	m.Routes.Update(func(tx *Trie) {
+		c := m.child(tx, m.root)
+		c.hosts = hosts
+		fn(c)
+		m.hosts.ports.Store(hosts.load())
+	})
 }
 
 // ServeHTTP exposes and serves the registered routes.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	for _, h := range m.requestHandlers {
+	for _, h := range m.getRequestHandlers() {
 		if h.Match(r) {
 			h.ServeHTTP(w, r)
 			return
@@ -189,16 +423,54 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	pw := m.paramsPool.Get().(*Writer)
 	pw.reset(w)
-	n := m.Routes.Search(path, pw)
+	var handler http.Handler
+	n, routes, hostParamsLen := m.search(r, path, pw)
+	if n != nil && n.pathValues && !n.stdMatch(pw.params[hostParamsLen:]) {
+		// the route of the HandleStd does not match the path like the ServeMux does, i.e an empty "{id}",
+		// the next route that the path matches serves the request.
+		n, handler = m.searchNext(r, path, n, routes, hostParamsLen, pw)
+	} else if n != nil {
+		handler = n.HandlerForRequest(r)
+		if handler == nil && n.methodFallback == nil && n.notAcceptable != nil {
+			// none of the conditions of the route passes, the next route that the path matches serves the request.
+			if next, nextHandler := m.searchNext(r, path, n, routes, hostParamsLen, pw); next != nil {
+				n, handler = next, nextHandler
+			} else {
+				handler = n.notAcceptable
+			}
+		}
+	}
+
+	if m.redirectSlash(r, path, n) {
+		m.paramsPool.Put(pw)
+		http.Redirect(w, r, (&url.URL{Path: r.URL.Path + pathSep, RawQuery: r.URL.RawQuery}).String(), http.StatusTemporaryRedirect)
+		return
+	}
+
 	if n != nil {
-		n.Handler.ServeHTTP(pw, r)
+		if n.pathValues || m.ParamsMode&ParamsInPathValue != 0 {
+			setPathValues(r, pw.params)
+		}
+
+		if m.ParamsMode&ParamsInContext != 0 {
+			r = WithParamStore(r, pw)
+		}
+	}
+
+	if handler != nil {
+		handler.ServeHTTP(pw.writer(), r)
+	} else if n != nil && n.methodFallback != nil {
+		pw.Header().Set("Allow", n.allowedMethods())
+		n.methodFallback.ServeHTTP(pw.writer(), r)
+	} else if notFound := m.errorHandlers.notFound(path); notFound != nil {
+		notFound.ServeHTTP(pw.writer(), r)
 	} else {
 		http.NotFound(w, r)
 		// or...
 		// http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		// w.WriteHeader(http.StatusNotFound)
-		// doesn't matter because the end-dev can customize the 404 with a root wildcard ("/*path")
-		// which will be fired if no other requested path's closest wildcard is found.
+		// doesn't matter because the end-dev can customize the 404 with `NotFound`
+		// or with a root wildcard ("/*path") which will be fired if no other requested path's closest wildcard is found.
 	}
 
 	m.paramsPool.Put(pw)
@@ -209,8 +481,21 @@ type SubMux interface {
 	Of(prefix string) SubMux
 	Unlink() SubMux
 	Use(middlewares ...Wrapper)
-	Handle(pattern string, handler http.Handler)
-	HandleFunc(pattern string, handlerFunc func(http.ResponseWriter, *http.Request))
+	NotFound(handler http.Handler)
+	MethodNotAllowed(handler http.Handler)
+	Handle(pattern string, handler http.Handler, options ...InsertOption)
+	HandleFunc(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	HandleMethod(method, pattern string, handler http.Handler, options ...InsertOption)
+	HandleMethodFunc(method, pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	HandleWhen(pattern string, matcher Matcher, handler http.Handler, options ...InsertOption)
+	HandleWhenFunc(pattern string, matcher Matcher, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	Get(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	Post(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	Put(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	Patch(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	Delete(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption)
+	Remove(pattern string) bool
+	ListRoutes() []Route
 	AbsPath() string
 }
 
@@ -228,9 +513,12 @@ func (m *Mux) Of(prefix string) SubMux {
 		return m
 	}
 
-	// modify prefix if it's already there on the parent.
-	if strings.HasPrefix(m.root, prefix) {
-		prefix = prefix[0:strings.LastIndex(m.root, prefix)]
+	// modify prefix if it's already there on the parent,
+	// as whole path segments, i.e "/api" of the "/api/v1" but not "/a" of the "/api".
+	if strings.HasPrefix(m.root, prefix) && (prefix[len(prefix)-1] == pathSepB || m.root[len(prefix)] == pathSepB) {
+		if prefix = prefix[0:strings.LastIndex(m.root, prefix)]; prefix == "" {
+			return m
+		}
 	}
 
 	// remove last slash "/", if any.
@@ -241,13 +529,30 @@ func (m *Mux) Of(prefix string) SubMux {
 	// remove any duplication of slashes "/".
 	prefix = pathSep + strings.Trim(m.root+prefix, pathSep)
 
-	return &Mux{
-		Routes: m.Routes,
+	return m.child(m.Routes, prefix)
+}
 
-		root:            prefix,
-		requestHandlers: m.requestHandlers[0:],
-		beginHandlers:   m.beginHandlers[0:],
+// child returns a new Mux which inherits this Mux' fields.
+func (m *Mux) child(routes *Trie, root string) *Mux {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := &Mux{
+		PathCorrection:           m.PathCorrection,
+		PathCorrectionNoRedirect: m.PathCorrectionNoRedirect,
+		ParamsMode:               m.ParamsMode,
+		Routes:                   routes,
+		paramsPool:               m.paramsPool,
+
+		root:           root,
+		beginHandlers:  m.beginHandlers[0:len(m.beginHandlers):len(m.beginHandlers)],
+		errorHandlers:  m.errorHandlers,
+		hosts:          m.hosts,
+		slashRedirects: m.slashRedirects,
 	}
+	c.requestHandlers.Store(m.getRequestHandlers())
+
+	return c
 }
 
 // AbsPath returns the absolute path of the router for this Mux group.
@@ -283,11 +588,17 @@ breaking changes and etc. This `Unlink`, which will return the same SubMux, it c
 //
 // mux := NewMux()
 // mux.Use(myLoggerMiddleware)
-// v1 := mux.Of("/v1").Unlink() // v1 will no longer have the "myLoggerMiddleware" or any Matchers.
+// v1 := mux.Of("/v1").Unlink() // v1 will no longer have the "myLoggerMiddleware", any Matchers or the NotFound and MethodNotAllowed handlers.
 // v1.HandleFunc("/users", myHandler)
 func (m *Mux) Unlink() SubMux {
-	m.requestHandlers = m.requestHandlers[0:0]
-	m.beginHandlers = m.beginHandlers[0:0]
+	m.mu.Lock()
+	m.requestHandlers.Store([]RequestHandler(nil))
+	m.beginHandlers = nil
+	m.mu.Unlock()
+
+	m.errorHandlers.update(m.root, func(entry *errorHandlersEntry) {
+		entry.unlinked = true
+	})
 
 	return m
 }
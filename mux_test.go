@@ -154,3 +154,248 @@ func TestMuxOf(t *testing.T) {
 	expect(t, http.MethodGet, srv.URL+"/v1").bodyEq("Handler of /v1")
 	expect(t, http.MethodGet, srv.URL+"/v1/hello").bodyEq("Handler of /v1/hello")
 }
+
+func TestMuxOfNested(t *testing.T) {
+	mux := NewMux()
+	api := mux.Of("/api")
+
+	tests := []struct {
+		mux      SubMux
+		prefix   string
+		expected string
+	}{
+		{api, "/a", "/api/a"},
+		{api, "/ap", "/api/ap"},
+		{api, "/api", "/api"},
+		{api.Of("/v1"), "/api", "/api/v1"},
+		{api.Of("/v1"), "/v2/", "/api/v1/v2"},
+		{api.Of("/a").Of("/b"), "/c", "/api/a/b/c"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mux.Of(tt.prefix).AbsPath(); got != tt.expected {
+			t.Fatalf("%s: Of(%q): expected: %s but got: %s", tt.mux.AbsPath(), tt.prefix, tt.expected, got)
+		}
+	}
+}
+
+func TestMuxRemove(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello"))
+	})
+
+	v1 := mux.Of("/v1")
+	v1.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Users"))
+	})
+
+	testHandler(t, mux, http.MethodGet, "/hello").statusCode(http.StatusOK).bodyEq("Hello")
+	testHandler(t, mux, http.MethodGet, "/v1/users").statusCode(http.StatusOK).bodyEq("Users")
+
+	if !v1.Remove("/users") {
+		t.Fatalf("expected /v1/users to be removed")
+	}
+	if mux.Remove("/users") {
+		t.Fatalf("expected /users to be not registered")
+	}
+
+	testHandler(t, mux, http.MethodGet, "/v1/users").statusCode(http.StatusNotFound)
+	testHandler(t, mux, http.MethodGet, "/hello").statusCode(http.StatusOK).bodyEq("Hello")
+
+	mux.Remove("/hello")
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello again"))
+	})
+	testHandler(t, mux, http.MethodGet, "/hello").statusCode(http.StatusOK).bodyEq("Hello again")
+}
+
+func TestMuxConcurrentRegistrations(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/static", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("static"))
+	})
+
+	const n = 50
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			pattern := fmt.Sprintf("/dynamic/%d/:id", i)
+			mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(GetParam(w, "id")))
+			})
+			mux.Use(func(next http.Handler) http.Handler { return next })
+			mux.HandleRequest(Host(fmt.Sprintf("host%d.localhost", i)), http.NotFoundHandler())
+			if i%2 == 0 {
+				mux.Remove(pattern)
+			}
+		}
+
+		mux.Batch(func(b SubMux) {
+			b.Remove("/static")
+			b.Of("/batch").HandleFunc("/static", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("batch static"))
+			})
+		})
+	}()
+
+	for i := 0; i < n*4; i++ {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static", nil))
+		if code := w.Code; code != http.StatusOK && code != http.StatusNotFound {
+			t.Fatalf("unexpected status code: %d", code)
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/batch/static", nil))
+		if w.Code == http.StatusOK {
+			// the batch is published at once.
+			testHandler(t, mux, http.MethodGet, "/static").statusCode(http.StatusNotFound)
+		}
+	}
+
+	<-done
+
+	testHandler(t, mux, http.MethodGet, "/static").statusCode(http.StatusNotFound)
+	testHandler(t, mux, http.MethodGet, "/batch/static").statusCode(http.StatusOK).bodyEq("batch static")
+	testHandler(t, mux, http.MethodGet, "/dynamic/1/42").statusCode(http.StatusOK).bodyEq("42")
+	testHandler(t, mux, http.MethodGet, "/dynamic/2/42").statusCode(http.StatusNotFound)
+}
+
+func TestMuxBatchHost(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("admin.app.com/settings", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("settings"))
+	})
+
+	request := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	mux.Batch(func(b SubMux) {
+		b.HandleFunc("admin.app.com/users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("users"))
+		})
+		b.HandleFunc(":tenant.app.com/home", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(GetParam(w, "tenant")))
+		})
+		b.Remove("admin.app.com/settings")
+
+		// the host routes are published with the rest of the batch.
+		if code := request("http://admin.app.com/users").Code; code != http.StatusNotFound {
+			t.Fatalf("expected the host route to not be visible before the end of the batch but got: %d", code)
+		}
+		if code := request("http://acme.app.com/home").Code; code != http.StatusNotFound {
+			t.Fatalf("expected the new host pattern to not be visible before the end of the batch but got: %d", code)
+		}
+		if body := request("http://admin.app.com/settings").Body.String(); body != "settings" {
+			t.Fatalf("expected the removed host route to be served until the end of the batch but got: %q", body)
+		}
+	})
+
+	if body := request("http://admin.app.com/users").Body.String(); body != "users" {
+		t.Fatalf("expected the host route of the batch but got: %q", body)
+	}
+	if body := request("http://acme.app.com/home").Body.String(); body != "acme" {
+		t.Fatalf("expected the host pattern of the batch but got: %q", body)
+	}
+	if code := request("http://admin.app.com/settings").Code; code != http.StatusNotFound {
+		t.Fatalf("expected the host route to be removed but got: %d", code)
+	}
+}
+
+func TestMuxBatchHostConcurrentHandle(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc(":tenant.app.com/home", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("home"))
+	})
+
+	const n = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			mux.HandleFunc(fmt.Sprintf(":tenant.app.com/handle/%d", i), func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("handle"))
+			})
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		mux.Batch(func(b SubMux) {
+			b.HandleFunc(fmt.Sprintf(":tenant.app.com/batch/%d", i), func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("batch"))
+			})
+		})
+	}
+	<-done
+
+	// the batches should not publish a copy of the host's routes that misses the routes of the Handle.
+	for i := 0; i < n; i++ {
+		for _, expected := range []string{"handle", "batch"} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://acme.app.com/%s/%d", expected, i), nil))
+			if body := w.Body.String(); body != expected {
+				t.Fatalf("expected the %s route %d but got: %d %q", expected, i, w.Code, body)
+			}
+		}
+	}
+}
+
+func TestMuxNotFound(t *testing.T) {
+	headerMiddleware := func(value string) Wrapper {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Middleware", value)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	notFound := func(message string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(message))
+		})
+	}
+
+	mux := NewMux()
+	mux.Use(headerMiddleware("root"))
+	mux.NotFound(notFound("root not found"))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	inheritor := mux.Of("/inheritor")
+	inheritor.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	custom := mux.Of("/custom")
+	custom.Use(headerMiddleware("custom"))
+	custom.NotFound(notFound("custom not found"))
+
+	orphan := mux.Of("/orphan").Unlink()
+	orphan.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	testHandler(t, mux, http.MethodGet, "/unknown").statusCode(http.StatusNotFound).
+		bodyEq("root not found").headerEq("X-Middleware", "root")
+	testHandler(t, mux, http.MethodGet, "/inheritor/unknown").statusCode(http.StatusNotFound).
+		bodyEq("root not found")
+	testHandler(t, mux, http.MethodGet, "/custom/unknown").statusCode(http.StatusNotFound).
+		bodyEq("custom not found")
+	testHandler(t, mux, http.MethodGet, "/customs").statusCode(http.StatusNotFound).
+		bodyEq("root not found")
+	testHandler(t, mux, http.MethodGet, "/orphan/unknown").statusCode(http.StatusNotFound).
+		bodyEq("404 page not found\n").headerEq("X-Middleware", "")
+
+	resp := testHandler(t, mux, http.MethodGet, "/custom/unknown").resp
+	if expected, got := []string{"root", "custom"}, resp.Header.Values("X-Middleware"); len(got) != 2 || got[0] != expected[0] || got[1] != expected[1] {
+		t.Fatalf("expected the middlewares: %v but got: %v", expected, got)
+	}
+
+	// a wildcard is still preferred.
+	mux.HandleFunc("/*path", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("wildcard"))
+	})
+	testHandler(t, mux, http.MethodGet, "/unknown").statusCode(http.StatusOK).bodyEq("wildcard")
+}
@@ -6,12 +6,18 @@ import (
 	"strings"
 )
 
-// Node is the trie's node which path patterns with their data like an HTTP handler are saved to.
-// See `Trie` too.
-type Node struct {
-	parent *Node
-
-	children               map[string]*Node
+// NodeOf is the trie's node which path patterns with their data like an HTTP handler are saved to.
+// The "T" is the type of its `Data`, only the `Data` is generic: the HTTP fields of the `Mux`,
+// i.e the `Handler` and the handlers per method, are part of every node, they are just unset outside of HTTP.
+// See `TrieOf` too.
+type NodeOf[T any] struct {
+	parent *NodeOf[T]
+	// the static path segments after this node's one, i.e "v1/settings",
+	// when the chain of the single static children of this node is merged to it, see `Trie#Compress`.
+	// It is next to the children as the search reads them together.
+	tail string
+
+	children               nodeChildren[T]
 	hasDynamicChild        bool // does one of the children contains a parameter or wildcard?
 	childNamedParameter    bool // is the child a named parameter (single segmnet)
 	childWildcardParameter bool // or it is a wildcard (can be more than one path segments) ?
@@ -21,12 +27,25 @@ type Node struct {
 	childPrefixLengths []int
 	childSuffixLengths []int
 
+	// the keys of the constrained named parameter children, i.e ":<int>", in order of registration.
+	childConstrainedParameters []string
+	// if not nil then this node is a constrained named parameter
+	// and the path segment should pass it in order to be matched.
+	constraint ParamConstraint
+
+	// the keys of the mixed parameters children, i.e ":{}.{}", the ones with the longest literal text first.
+	childMixedParameters []string
+	// if not nil then this node is a path segment with mixed parameters and literal text, i.e ":name.:ext".
+	mixed *mixedSegment
+
 	pathIndex  int
 	paramCount int
 
 	paramKeys []string // the param keys without : or *.
 	end       bool     // it is a complete node, here we stop and we can say that the node is valid.
 	key       string   // if end == true then key is filled with the original value of the insertion's key.
+	// if true then the key has optional parts, i.e "/reports/:year?", and this node is one of its expansions.
+	optional bool
 	// if key != "" && its parent has childWildcardParameter == true,
 	// we need it to track the static part for the closest-wildcard's parameter storage.
 	staticKey string
@@ -35,70 +54,333 @@ type Node struct {
 	Handler http.Handler
 	Tag     string
 
+	// the handlers per HTTP method, see `WithMethodHandler`.
+	methodHandlers map[string]http.Handler
+	// the handlers which serve only the requests that pass their matchers, in order of registration, see `WithMatcher`.
+	matchedHandlers []matchedHandler
+	// methodFallback answers the OPTIONS and the not allowed methods, see `Mux#HandleMethod`.
+	methodFallback http.Handler
+	// notAcceptable answers the requests that pass none of the matchers of the conditional handlers
+	// when no other route serves them, see `Mux#HandleWhen`.
+	notAcceptable http.Handler
+	// if true then the Mux sets the path parameters to the `Request.PathValue`, see `Mux#HandleStd`.
+	pathValues bool
+	// if true then the wildcard of the node matches only an empty path, i.e the "/path/{$}" of the `Mux#HandleStd`.
+	exactSlash bool
+	// if true then the path without the trailing slash is redirected to this node, i.e the "/path/" of the `Mux#HandleStd`.
+	redirectSlash bool
+	// the number of the Mux middlewares that wrap the handlers, see `Route`.
+	middlewares int
+	// the MethodHandler which was registered as the Handler, if any,
+	// it is kept because the middlewares hide it, see `Route`.
+	methodHandler *MethodHandler
+	// the conflicts of the insertions of this node's path pattern, see `Trie#Validate`.
+	warnings []RouteWarning
+
 	// other insert data.
-	Data interface{}
+	Data T
 }
 
+// Node is the node of the `Trie`, its `Data` can be anything.
+type Node = NodeOf[interface{}]
+
 // NewNode returns a new, empty, Node.
 func NewNode() *Node {
 	n := new(Node)
 	return n
 }
 
-func (n *Node) addChild(s string, child *Node) {
-	if n.children == nil {
-		n.children = make(map[string]*Node)
+// clone returns a deep copy of this node and its children, the "parent" is the parent of the copy.
+func (n *NodeOf[T]) clone(parent *NodeOf[T]) *NodeOf[T] {
+	c := new(NodeOf[T])
+	*c = *n
+	c.parent = parent
+	c.childPrefixLengths = append([]int(nil), n.childPrefixLengths...)
+	c.childSuffixLengths = append([]int(nil), n.childSuffixLengths...)
+	c.childConstrainedParameters = append([]string(nil), n.childConstrainedParameters...)
+	c.childMixedParameters = append([]string(nil), n.childMixedParameters...)
+	c.warnings = append([]RouteWarning(nil), n.warnings...)
+	c.matchedHandlers = append([]matchedHandler(nil), n.matchedHandlers...)
+
+	if n.methodHandlers != nil {
+		c.methodHandlers = make(map[string]http.Handler, len(n.methodHandlers))
+		for method, handler := range n.methodHandlers {
+			c.methodHandlers[method] = handler
+		}
+	}
+
+	// the copy keeps the layout of the children.
+	c.children = nodeChildren[T]{}
+	if n.children.m != nil {
+		c.children.m = make(map[string]*NodeOf[T], len(n.children.m))
+		for s, child := range n.children.m {
+			c.children.m[s] = child.clone(c)
+		}
+	} else if len(n.children.sorted) > 0 {
+		c.children.sorted = make([]nodeChild[T], len(n.children.sorted))
+		for i, sc := range n.children.sorted {
+			c.children.sorted[i] = nodeChild[T]{key: sc.key, node: sc.node.clone(c)}
+		}
+	}
+
+	return c
+}
+
+// defaultMaxSortedChildren is the number of children that a node keeps in a sorted slice, see `nodeChildren`.
+// Zero stores all the children in maps like the previous versions, the benchmarks compare the two.
+const defaultMaxSortedChildren = 8
+
+// nodeChild is a child node and its key.
+type nodeChild[T any] struct {
+	key  string
+	node *NodeOf[T]
+}
+
+// nodeChildren are the children of a node by their keys.
+// Most of the nodes have a few children, they are kept in a slice sorted by key which takes less memory
+// than a map and it is as fast to search, a node with more than the trie's `maxSortedChildren` children uses a map.
+type nodeChildren[T any] struct {
+	sorted []nodeChild[T]
+	m      map[string]*NodeOf[T]
+}
+
+func (c *nodeChildren[T]) len() int {
+	if c.m != nil {
+		return len(c.m)
+	}
+
+	return len(c.sorted)
+}
+
+// index returns the index of the "key" in the sorted slice, or the index that it should be inserted at.
+func (c *nodeChildren[T]) index(key string) int {
+	lo, hi := 0, len(c.sorted)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if c.sorted[mid].key < key {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
 	}
 
-	if _, exists := n.children[s]; exists {
+	return lo
+}
+
+func (c *nodeChildren[T]) get(key string) *NodeOf[T] {
+	if c.m != nil {
+		return c.m[key]
+	}
+
+	if i := c.index(key); i < len(c.sorted) && c.sorted[i].key == key {
+		return c.sorted[i].node
+	}
+
+	return nil
+}
+
+// set adds or replaces the child of the "key",
+// the children are moved to a map when a new child would exceed the "maxSorted".
+func (c *nodeChildren[T]) set(key string, child *NodeOf[T], maxSorted int) {
+	if c.m != nil {
+		c.m[key] = child
 		return
 	}
 
-	child.parent = n
-	n.children[s] = child
+	i := c.index(key)
+	if i < len(c.sorted) && c.sorted[i].key == key {
+		c.sorted[i].node = child
+		return
+	}
+
+	if len(c.sorted) >= maxSorted {
+		c.m = make(map[string]*NodeOf[T], len(c.sorted)+1)
+		for _, sc := range c.sorted {
+			c.m[sc.key] = sc.node
+		}
+		c.m[key] = child
+		c.sorted = nil
+		return
+	}
+
+	c.sorted = append(c.sorted, nodeChild[T]{})
+	copy(c.sorted[i+1:], c.sorted[i:])
+	c.sorted[i] = nodeChild[T]{key: key, node: child}
 }
 
-func (n *Node) addPrefixLength(l int) {
-	addLength(&n.childPrefixLengths, l)
+func (c *nodeChildren[T]) delete(key string) {
+	if c.m != nil {
+		delete(c.m, key)
+		return
+	}
+
+	if i := c.index(key); i < len(c.sorted) && c.sorted[i].key == key {
+		copy(c.sorted[i:], c.sorted[i+1:])
+		c.sorted[len(c.sorted)-1] = nodeChild[T]{}
+		c.sorted = c.sorted[:len(c.sorted)-1]
+	}
 }
 
-func (n *Node) addSuffixLength(l int) {
-	addLength(&n.childSuffixLengths, l)
+// replace replaces the child of the existing "key", the layout of the children does not change.
+func (c *nodeChildren[T]) replace(key string, child *NodeOf[T]) {
+	if c.m != nil {
+		c.m[key] = child
+		return
+	}
+
+	if i := c.index(key); i < len(c.sorted) && c.sorted[i].key == key {
+		c.sorted[i].node = child
+	}
 }
 
-func addLength(paramSlice *[]int, l int) {
-	if paramSlice == nil {
-		paramSlice = &[]int{l}
-	} else {
-		*paramSlice = append(*paramSlice, l)
-		paramDesc := []int{}
-		m := map[int]struct{}{}
-		for _, val := range *paramSlice {
-			if _, ok := m[val]; !ok {
-				m[val] = struct{}{}
-				paramDesc = append(paramDesc, val)
+// keyOf returns the key of the "child".
+func (c *nodeChildren[T]) keyOf(child *NodeOf[T]) (string, bool) {
+	if c.m != nil {
+		for key, node := range c.m {
+			if node == child {
+				return key, true
+			}
+		}
+		return "", false
+	}
+
+	for _, sc := range c.sorted {
+		if sc.node == child {
+			return sc.key, true
+		}
+	}
+
+	return "", false
+}
+
+// each calls the "fn" for each child, the small sets of children are visited in order of their keys.
+func (c *nodeChildren[T]) each(fn func(key string, child *NodeOf[T])) {
+	if c.m != nil {
+		for key, child := range c.m {
+			fn(key, child)
+		}
+		return
+	}
+
+	for _, sc := range c.sorted {
+		fn(sc.key, sc.node)
+	}
+}
+
+func (n *NodeOf[T]) addChild(s string, child *NodeOf[T], maxSorted int) {
+	if n.children.get(s) != nil {
+		return
+	}
+
+	child.parent = n
+	n.children.set(s, child, maxSorted)
+}
+
+// removeChild removes the "child" node and updates the dynamic child flags
+// and the prefix and suffix lengths of this node.
+func (n *NodeOf[T]) removeChild(child *NodeOf[T]) {
+	s, ok := n.children.keyOf(child)
+	if !ok {
+		return
+	}
+
+	n.children.delete(s)
+	child.parent = nil
+
+	switch {
+	case child.mixed != nil:
+		for i, key := range n.childMixedParameters {
+			if key == s {
+				n.childMixedParameters = append(n.childMixedParameters[:i:i], n.childMixedParameters[i+1:]...)
+				break
+			}
+		}
+	case s == ParamStart:
+		n.childNamedParameter = false
+	case s == WildcardParamStart:
+		n.childWildcardParameter = false
+	case strings.HasPrefix(s, ParamStart+ParamConstraintStart):
+		for i, key := range n.childConstrainedParameters {
+			if key == s {
+				n.childConstrainedParameters = append(n.childConstrainedParameters[:i:i], n.childConstrainedParameters[i+1:]...)
+				break
 			}
 		}
-		sort.Slice(paramDesc, func(i, j int) bool {
-			return paramDesc[i] > paramDesc[j]
+	case strings.HasSuffix(s, PrefixParamStart):
+		n.childPrefixLengths = n.childPrefixLengths[0:0]
+		n.children.each(func(key string, _ *NodeOf[T]) {
+			if strings.HasSuffix(key, PrefixParamStart) {
+				n.addPrefixLength(len(key) - len(PrefixParamStart))
+			}
 		})
-		paramSlice = &paramDesc
+		n.childPrefixParameter = len(n.childPrefixLengths) > 0
+	case strings.HasPrefix(s, SuffixParamStart):
+		n.childSuffixLengths = n.childSuffixLengths[0:0]
+		n.children.each(func(key string, _ *NodeOf[T]) {
+			if strings.HasPrefix(key, SuffixParamStart) {
+				n.addSuffixLength(len(key) - len(SuffixParamStart))
+			}
+		})
+		n.childSuffixParameter = len(n.childSuffixLengths) > 0
 	}
+
+	n.hasDynamicChild = n.childNamedParameter || n.childWildcardParameter ||
+		n.childPrefixParameter || n.childSuffixParameter || len(n.childConstrainedParameters) > 0 ||
+		len(n.childMixedParameters) > 0
 }
 
-func (n *Node) getChild(s string) *Node {
-	if n.children == nil {
-		return nil
+// reset clears the data of a complete node, the node is no longer a valid one.
+func (n *NodeOf[T]) reset() {
+	n.end = false
+	n.key = ""
+	n.optional = false
+	n.staticKey = ""
+	n.paramKeys = nil
+	n.Handler = nil
+	n.methodHandlers = nil
+	n.matchedHandlers = nil
+	n.methodFallback = nil
+	n.notAcceptable = nil
+	n.pathValues = false
+	n.exactSlash = false
+	n.redirectSlash = false
+	n.middlewares = 0
+	n.methodHandler = nil
+	n.warnings = nil
+	n.Tag = ""
+	var data T
+	n.Data = data
+}
+
+func (n *NodeOf[T]) addPrefixLength(l int) {
+	addLength(&n.childPrefixLengths, l)
+}
+
+func (n *NodeOf[T]) addSuffixLength(l int) {
+	addLength(&n.childSuffixLengths, l)
+}
+
+// addLength adds the "l" to the "paramSlice" and keeps it unique and in descending order.
+func addLength(paramSlice *[]int, l int) {
+	for _, val := range *paramSlice {
+		if val == l {
+			return
+		}
 	}
 
-	return n.children[s]
+	*paramSlice = append(*paramSlice, l)
+	sort.Sort(sort.Reverse(sort.IntSlice(*paramSlice)))
 }
 
-func (n *Node) hasChild(s string) bool {
+func (n *NodeOf[T]) getChild(s string) *NodeOf[T] {
+	return n.children.get(s)
+}
+
+func (n *NodeOf[T]) hasChild(s string) bool {
 	return n.getChild(s) != nil
 }
 
-func (n *Node) getPrefixParamChild(s string) (*Node, bool) {
+func (n *NodeOf[T]) getPrefixParamChild(s string) (*NodeOf[T], bool) {
 	if !n.childPrefixParameter {
 		return nil, false
 	}
@@ -116,7 +398,7 @@ func (n *Node) getPrefixParamChild(s string) (*Node, bool) {
 	return nil, false
 }
 
-func (n *Node) getSuffixParamChild(s string) (*Node, bool) {
+func (n *NodeOf[T]) getSuffixParamChild(s string) (*NodeOf[T], bool) {
 	if !n.childSuffixParameter {
 		return nil, false
 	}
@@ -135,7 +417,17 @@ func (n *Node) getSuffixParamChild(s string) (*Node, bool) {
 	return nil, false
 }
 
-func (n *Node) findClosestParentWildcardNode() *Node {
+func (n *NodeOf[T]) getConstrainedParamChild(s string) *NodeOf[T] {
+	for _, key := range n.childConstrainedParameters {
+		if child := n.getChild(key); child.constraint(s) {
+			return child
+		}
+	}
+
+	return nil
+}
+
+func (n *NodeOf[T]) findClosestParentWildcardNode() *NodeOf[T] {
 	n = n.parent
 	for n != nil {
 		if n.childWildcardParameter {
@@ -147,32 +439,133 @@ func (n *Node) findClosestParentWildcardNode() *Node {
 	return nil
 }
 
-func (n *Node) findClosestUnvisitedNode(visitedNodes map[*Node]struct{}, path string, i int) (*Node, int, int) {
-	n = n.parent
-	var start int
-	for n != nil {
-		i = strings.LastIndex(path[:i], pathSep)
+// visitedNodes is the set of the parameter nodes that a search went through.
+// A search visits a few of them, so they fit to a fixed array and the set does not allocate.
+type visitedNodes[T any] struct {
+	fixed [8]*NodeOf[T]
+	n     int
+	more  []*NodeOf[T]
+}
+
+func (v *visitedNodes[T]) add(n *NodeOf[T]) {
+	if v.n < len(v.fixed) {
+		v.fixed[v.n] = n
+		v.n++
+		return
+	}
+
+	v.more = append(v.more, n)
+}
+
+func (v *visitedNodes[T]) has(n *NodeOf[T]) bool {
+	for i := 0; i < v.n; i++ {
+		if v.fixed[i] == n {
+			return true
+		}
+	}
+
+	for _, m := range v.more {
+		if m == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pathSegment returns the path segment at "index", like the strings.Split(path, "/")[index] but without allocations,
+// the index 0 is the empty segment before the first slash. It returns an empty string if there is no such segment.
+func pathSegment(path string, index int) string {
+	for ; index > 0; index-- {
+		i := strings.IndexByte(path, pathSepB)
 		if i == -1 {
-			i = 0
+			return ""
 		}
-		start = strings.LastIndex(path[:i], pathSep) + 1
-		segment := strings.Split(path, pathSep)[n.pathIndex]
+		path = path[i+1:]
+	}
+
+	if i := strings.IndexByte(path, pathSepB); i != -1 {
+		return path[:i]
+	}
+
+	return path
+}
+
+// unvisitedLevels returns the number of the path segments that this node took, the "partial" is the number
+// of the path segments of this compressed node that the search went through, 0 for all of them, see `Trie#Compress`.
+func (n *NodeOf[T]) unvisitedLevels(partial int) int {
+	if partial > 0 {
+		return partial
+	}
+
+	return n.levels()
+}
+
+// findClosestUnvisitedNode accepts both the original "q" and the (maybe lowercased) "path",
+// the constraints are validated against the original path segment.
+// The "levels" is the number of the path segments to go back before the first parent is checked,
+// the path segments that this node took, see `unvisitedLevels`.
+// If "constrainedOnly" is true then only the named parameters next to the visited constrained ones are returned,
+// so a path segment which passes a constraint can still match a plain parameter, see `Trie#Search`.
+func (n *NodeOf[T]) findClosestUnvisitedNode(visited *visitedNodes[T], q, path string, i, levels int, constrainedOnly bool) (*NodeOf[T], int, int) {
+	var (
+		start int
+		// the values of the mixed parameters are not needed, just a place to match them.
+		buf [8]string
+	)
+	for n.parent != nil {
+		// go back as many path segments as the node takes.
+		for ; levels > 0; levels-- {
+			i = strings.LastIndexByte(path[:i], pathSepB)
+			if i == -1 {
+				i = 0
+			}
+		}
+		n = n.parent
+		levels = n.levels()
+
+		start = strings.LastIndexByte(path[:i], pathSepB) + 1
+		segment := pathSegment(path, n.pathIndex)
+		if constrainedOnly {
+			if child := n.getConstrainedParamChild(pathSegment(q, n.pathIndex)); child != nil && visited.has(child) && n.childNamedParameter {
+				if named := n.getChild(ParamStart); !visited.has(named) {
+					return named, start, i
+				}
+			}
+			continue
+		}
+
 		if child, exists := n.getPrefixParamChild(segment); exists {
-			if _, visited := visitedNodes[child]; !visited {
+			if !visited.has(child) {
 				return child, start, i
 			}
 		} else if child, exists := n.getSuffixParamChild(segment); exists {
-			if _, visited := visitedNodes[child]; !visited {
+			if !visited.has(child) {
 				return child, start, i
 			}
+		} else if child, _ := n.getMixedParamChild(pathSegment(q, n.pathIndex), segment, buf[:0]); child != nil {
+			if !visited.has(child) {
+				return child, start, i
+			}
+		} else if child := n.getConstrainedParamChild(pathSegment(q, n.pathIndex)); child != nil {
+			if !visited.has(child) {
+				return child, start, i
+			}
+
+			// the constrained one is already explored, give a chance to the named one.
+			if n.childNamedParameter {
+				if child := n.getChild(ParamStart); child != nil {
+					if !visited.has(child) {
+						return child, start, i
+					}
+				}
+			}
 		} else if n.childNamedParameter {
 			child := n.getChild(ParamStart)
-			if _, visited := visitedNodes[child]; !visited {
+			if !visited.has(child) {
 				return child, start, i
 			}
 		}
-
-		n = n.parent
 	}
 
 	return nil, start, i
@@ -191,7 +584,7 @@ var DefaultKeysSorter = func(list []string) func(i, j int) bool {
 
 // Keys returns this node's key (if it's a final path segment)
 // and its children's node's key. The "sorter" can be optionally used to sort the result.
-func (n *Node) Keys(sorter NodeKeysSorter) (list []string) {
+func (n *NodeOf[T]) Keys(sorter NodeKeysSorter) (list []string) {
 	if n == nil {
 		return
 	}
@@ -200,11 +593,9 @@ func (n *Node) Keys(sorter NodeKeysSorter) (list []string) {
 		list = append(list, n.key)
 	}
 
-	if n.children != nil {
-		for _, child := range n.children {
-			list = append(list, child.Keys(sorter)...)
-		}
-	}
+	n.children.each(func(_ string, child *NodeOf[T]) {
+		list = append(list, child.Keys(sorter)...)
+	})
 
 	if sorter != nil {
 		sort.Slice(list, sorter(list))
@@ -214,16 +605,68 @@ func (n *Node) Keys(sorter NodeKeysSorter) (list []string) {
 }
 
 // Parent returns the parent of that node, can return nil if this is the root node.
-func (n *Node) Parent() *Node {
+func (n *NodeOf[T]) Parent() *NodeOf[T] {
 	return n.parent
 }
 
+// HandlerFor returns the handler which is responsible for the HTTP "method",
+// a HEAD request is handled by the GET handler if there is no HEAD one.
+// If there is no handler for that method then it returns the `Handler` field, which may be nil.
+//
+// See `WithMethodHandler`.
+func (n *NodeOf[T]) HandlerFor(method string) http.Handler {
+	if handler, ok := n.methodHandlers[method]; ok {
+		return handler
+	}
+
+	if method == http.MethodHead {
+		if handler, ok := n.methodHandlers[http.MethodGet]; ok {
+			return handler
+		}
+	}
+
+	return n.Handler
+}
+
+// Methods returns the HTTP methods that this node has handlers for, sorted.
+//
+// See `WithMethodHandler`.
+func (n *NodeOf[T]) Methods() []string {
+	if len(n.methodHandlers) == 0 {
+		return nil
+	}
+
+	methods := make([]string, 0, len(n.methodHandlers))
+	for method := range n.methodHandlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// allowedMethods returns the value of the "Allow" header for this node,
+// including the automatically handled HEAD and OPTIONS methods.
+func (n *NodeOf[T]) allowedMethods() string {
+	methods := n.Methods()
+	if _, ok := n.methodHandlers[http.MethodGet]; ok {
+		if _, ok = n.methodHandlers[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	if _, ok := n.methodHandlers[http.MethodOptions]; !ok {
+		methods = append(methods, http.MethodOptions)
+	}
+
+	return strings.Join(methods, ", ")
+}
+
 // String returns the key, which is the path pattern for the HTTP Mux.
-func (n *Node) String() string {
+func (n *NodeOf[T]) String() string {
 	return n.key
 }
 
 // IsEnd returns true if this Node is a final path, has a key.
-func (n *Node) IsEnd() bool {
+func (n *NodeOf[T]) IsEnd() bool {
 	return n.end
 }
@@ -0,0 +1,567 @@
+package muxie
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification that the generated documents follow.
+const OpenAPIVersion = "3.0.3"
+
+// OpenAPIOperation holds the documentation of a route's operation(s), see `WithOpenAPI` and `Mux#OpenAPI`.
+type OpenAPIOperation struct {
+	// Method is the HTTP method of the operation, i.e "GET",
+	// if empty then it documents all the methods of the route.
+	Method      string
+	Summary     string
+	Description string
+	// OperationID defaults to the route's tag (see `WithTag`) if the route has a single operation.
+	OperationID string
+	Tags        []string
+	Deprecated  bool
+	// Request is a value of the Go type of the JSON request body, i.e `User{}`, if any.
+	Request interface{}
+	// Response is a value of the Go type of the JSON response body, i.e `[]User{}`, if any.
+	Response interface{}
+	// ResponseStatus is the status code of a successful response, defaults to 200.
+	ResponseStatus int
+	// Hidden excludes the operation from the document.
+	Hidden bool
+}
+
+// OpenAPIOperations is the type of the `Node#Data` that the `WithOpenAPI` sets.
+type OpenAPIOperations []OpenAPIOperation
+
+// WithOpenAPI documents the route's operations for the `Mux#OpenAPI`, i.e:
+//
+//	mux.Get("/users/:id<int>", getUser, muxie.WithOpenAPI(muxie.OpenAPIOperation{
+//		Summary:  "Get a user",
+//		Response: User{},
+//	}))
+//
+// The operations are stored to the node's `Data` as `OpenAPIOperations`
+// and they are appended to the existing ones, so each method of a route can be documented separately
+// through the `OpenAPIOperation.Method` field.
+// Note that it replaces any other type of `Data`, an `OpenAPIOperation` can be set through the `WithData` as well.
+func WithOpenAPI(operations ...OpenAPIOperation) InsertOption {
+	return func(n *Node) {
+		existing, _ := n.Data.(OpenAPIOperations)
+		n.Data = append(existing[0:len(existing):len(existing)], operations...)
+	}
+}
+
+// openAPIOperations returns the documentation of a route, if any.
+func openAPIOperations(data interface{}) OpenAPIOperations {
+	switch v := data.(type) {
+	case OpenAPIOperations:
+		return v
+	case []OpenAPIOperation:
+		return v
+	case OpenAPIOperation:
+		return OpenAPIOperations{v}
+	case *OpenAPIOperation:
+		if v != nil {
+			return OpenAPIOperations{*v}
+		}
+	}
+
+	return nil
+}
+
+// of returns the documentation of the "method",
+// the one with the same method is preferred over the first one without a method.
+func (operations OpenAPIOperations) of(method string) (operation OpenAPIOperation) {
+	found := false
+	for _, op := range operations {
+		if normalizeMethod(op.Method) == method {
+			return op
+		}
+
+		if op.Method == "" && !found {
+			operation, found = op, true
+		}
+	}
+
+	return
+}
+
+// OpenAPIInfo is the info object of an OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIDocument is an OpenAPI 3 document, see `Mux#OpenAPI`.
+type OpenAPIDocument struct {
+	OpenAPI    string                     `json:"openapi"`
+	Info       OpenAPIInfo                `json:"info"`
+	Paths      map[string]OpenAPIPathItem `json:"paths"`
+	Components *OpenAPIComponents         `json:"components,omitempty"`
+}
+
+// OpenAPIPathItem holds the operations of a path by their lowercase HTTP methods.
+type OpenAPIPathItem map[string]*OpenAPIOperationObject
+
+// OpenAPIOperationObject is the operation object of an OpenAPI document.
+type OpenAPIOperationObject struct {
+	Tags        []string                   `json:"tags,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	OperationID string                     `json:"operationId,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+}
+
+// OpenAPIParameter is the parameter object of an OpenAPI document.
+type OpenAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required"`
+	Schema      *JSONSchema `json:"schema"`
+}
+
+// OpenAPIRequestBody is the request body object of an OpenAPI document.
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse is the response object of an OpenAPI document.
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType is the media type object of an OpenAPI document.
+type OpenAPIMediaType struct {
+	Schema *JSONSchema `json:"schema"`
+}
+
+// OpenAPIComponents holds the schemas of the named Go types, they are referenced by their names.
+type OpenAPIComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// JSONSchema is the (OpenAPI 3.0 subset of) JSON Schema of a parameter, a request or a response body.
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+}
+
+// JSON returns the document encoded as JSON.
+func (doc *OpenAPIDocument) JSON() ([]byte, error) {
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// YAML returns the document encoded as YAML.
+func (doc *OpenAPIDocument) YAML() ([]byte, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonToYAML(b)
+}
+
+// OpenAPI generates an OpenAPI 3 document from the routes of this Mux, see `ListRoutes`.
+//
+// Named, prefix and suffix parameters become path parameters, their constraints become their schemas.
+// A wildcard becomes a path parameter too, documented as a catch-all which may contain slashes.
+// The methods of the route become its operations, a route that handles all methods
+// through a single handler is documented as a "GET" operation, unless its documentation specifies the methods.
+// The summaries, the Go types of the request and response bodies and the tags of the operations
+// are set through the `WithOpenAPI`. The routes of the host patterns are not documented.
+func (m *Mux) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]OpenAPIPathItem),
+	}
+	schemas := newOpenAPISchemas()
+
+	// OpenAPI has no optional path parameters, a pattern with optional parts is documented per expansion.
+	var routes []Route
+	for _, route := range m.ListRoutes() {
+		if route.Host == "" {
+			routes = append(routes, route.expand()...)
+		}
+	}
+
+	for _, route := range routes {
+		path, parameters := openAPIPath(route)
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(OpenAPIPathItem)
+		}
+
+		docs := openAPIOperations(route.Data)
+		methods := openAPIMethods(route, docs)
+		for _, method := range methods {
+			opDoc := docs.of(method)
+
+			key := strings.ToLower(method)
+			if opDoc.Hidden || item[key] != nil {
+				continue
+			}
+
+			op := &OpenAPIOperationObject{
+				Tags:        opDoc.Tags,
+				Summary:     opDoc.Summary,
+				Description: opDoc.Description,
+				OperationID: opDoc.OperationID,
+				Parameters:  parameters,
+				Deprecated:  opDoc.Deprecated,
+			}
+			if op.OperationID == "" && len(methods) == 1 {
+				op.OperationID = route.Tag
+			}
+
+			if opDoc.Request != nil {
+				op.RequestBody = &OpenAPIRequestBody{
+					Required: true,
+					Content:  map[string]OpenAPIMediaType{"application/json": {Schema: schemas.of(reflect.TypeOf(opDoc.Request))}},
+				}
+			}
+
+			status := opDoc.ResponseStatus
+			if status == 0 {
+				status = http.StatusOK
+			}
+			response := OpenAPIResponse{Description: http.StatusText(status)}
+			if opDoc.Response != nil {
+				response.Content = map[string]OpenAPIMediaType{"application/json": {Schema: schemas.of(reflect.TypeOf(opDoc.Response))}}
+			}
+			op.Responses = map[string]OpenAPIResponse{strconv.Itoa(status): response}
+
+			item[key] = op
+		}
+
+		if len(item) > 0 {
+			doc.Paths[path] = item
+		}
+	}
+
+	if len(schemas.components) > 0 {
+		doc.Components = &OpenAPIComponents{Schemas: schemas.components}
+	}
+
+	return doc
+}
+
+// HandleOpenAPI registers a route which serves the `OpenAPI` document of this Mux,
+// the document is encoded as YAML if the "pattern" ends with ".yaml" or ".yml", otherwise as JSON, i.e:
+//
+//	mux.HandleOpenAPI("/openapi.json", muxie.OpenAPIInfo{Title: "Users API", Version: "1.0.0"})
+//
+// The document is generated on each request, so it is always up to date with the routes.
+// The route itself is not part of the document.
+func (m *Mux) HandleOpenAPI(pattern string, info OpenAPIInfo) {
+	asYAML := strings.HasSuffix(pattern, ".yaml") || strings.HasSuffix(pattern, ".yml")
+
+	m.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			b   []byte
+			err error
+		)
+
+		doc := m.OpenAPI(info)
+		if asYAML {
+			w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+			b, err = doc.YAML()
+		} else {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			b, err = doc.JSON()
+		}
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(b)
+	}), WithOpenAPI(OpenAPIOperation{Hidden: true}))
+}
+
+// openAPIMethods returns the HTTP methods that the operations of the route are documented for.
+func openAPIMethods(route Route, docs OpenAPIOperations) []string {
+	if len(route.Methods) > 0 {
+		return route.Methods
+	}
+
+	var methods []string
+	for _, d := range docs {
+		if d.Method != "" {
+			methods = append(methods, normalizeMethod(d.Method))
+		}
+	}
+
+	if len(methods) == 0 {
+		return []string{http.MethodGet}
+	}
+
+	sort.Strings(methods)
+	return methods
+}
+
+// openAPIPath converts the route's pattern to an OpenAPI path and its parameters,
+// i.e "/users/:id" to "/users/{id}".
+func openAPIPath(route Route) (string, []OpenAPIParameter) {
+	if len(route.Params) == 0 {
+		return route.Pattern, nil
+	}
+
+	var (
+		b          strings.Builder
+		parameters []OpenAPIParameter
+		params     = route.Params
+	)
+
+	for _, s := range slowPathSplit(route.Pattern) {
+		b.WriteString(pathSep)
+		if !isParamSegment(s) {
+			b.WriteString(s)
+			continue
+		}
+
+		if isMixedParam(s) {
+			m, err := parseMixedSegment(s, false)
+			if err != nil {
+				b.WriteString(s)
+				continue
+			}
+
+			for _, part := range m.parts {
+				if part.name == "" {
+					b.WriteString(part.literal)
+					continue
+				}
+
+				params = params[1:]
+				b.WriteString("{" + part.name + "}")
+				parameters = append(parameters, OpenAPIParameter{Name: part.name, In: "path", Required: true, Schema: constraintSchema(part.constraintKey)})
+			}
+			continue
+		}
+
+		p := params[0]
+		params = params[1:]
+
+		name := p.Name
+		if name == "" {
+			name = "wildcard"
+		}
+
+		parameter := OpenAPIParameter{Name: name, In: "path", Required: true, Schema: &JSONSchema{Type: "string"}}
+		switch p.Kind {
+		case WildcardParam:
+			parameter.Description = "Catch-all, the rest of the path which may contain slashes."
+		case NamedParam:
+			parameter.Schema = constraintSchema(p.Constraint)
+		}
+
+		if p.Kind == PrefixParam {
+			b.WriteString(p.Affix)
+		}
+		b.WriteString("{" + name + "}")
+		if p.Kind == SuffixParam {
+			b.WriteString(p.Affix)
+		}
+
+		parameters = append(parameters, parameter)
+	}
+
+	return b.String(), parameters
+}
+
+// isParamSegment reports whether the path segment "s" of a pattern holds a parameter.
+func isParamSegment(s string) bool {
+	return s[0] == ParamStart[0] || s[0] == WildcardParamStart[0] || isPrefixParam(s) || isSuffixParam(s) || isMixedParam(s)
+}
+
+// constraintSchema returns the schema of a named parameter's built-in constraint, see `RegisterParamConstraint`.
+func constraintSchema(constraint string) *JSONSchema {
+	switch {
+	case constraint == "int":
+		return &JSONSchema{Type: "integer", Format: "int64"}
+	case constraint == "uint":
+		min := 0.0
+		return &JSONSchema{Type: "integer", Format: "int64", Minimum: &min}
+	case constraint == "alpha":
+		return &JSONSchema{Type: "string", Pattern: "^[a-zA-Z]+$"}
+	case constraint == "uuid":
+		return &JSONSchema{Type: "string", Format: "uuid"}
+	case strings.HasPrefix(constraint, "regex(") && strings.HasSuffix(constraint, ")"):
+		return &JSONSchema{Type: "string", Pattern: "^(?:" + constraint[len("regex("):len(constraint)-1] + ")$"}
+	default:
+		return &JSONSchema{Type: "string"}
+	}
+}
+
+// openAPISchemas reflects Go types into JSON Schemas,
+// the named struct types are stored to the components and they are referenced by their names.
+type openAPISchemas struct {
+	components map[string]*JSONSchema
+	names      map[reflect.Type]string
+}
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{
+		components: make(map[string]*JSONSchema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	jsonRawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (s *openAPISchemas) of(typ reflect.Type) *JSONSchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ {
+	case timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case jsonRawMessageType:
+		return &JSONSchema{}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &JSONSchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &JSONSchema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		min := 0.0
+		return &JSONSchema{Type: "integer", Minimum: &min}
+	case reflect.Float32:
+		return &JSONSchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &JSONSchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes a []byte as a base64 string.
+			return &JSONSchema{Type: "string", Format: "byte"}
+		}
+		return &JSONSchema{Type: "array", Items: s.of(typ.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: s.of(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return s.object(typ)
+		}
+		return &JSONSchema{Ref: "#/components/schemas/" + s.component(typ)}
+	default:
+		// interfaces and anything else can be any value.
+		return &JSONSchema{}
+	}
+}
+
+// component returns the name of the named struct type "typ" in the components,
+// the type's schema is stored the first time.
+func (s *openAPISchemas) component(typ reflect.Type) string {
+	if name, ok := s.names[typ]; ok {
+		return name
+	}
+
+	name := typ.Name()
+	if _, taken := s.components[name]; taken {
+		// same name, different package.
+		name = strings.Replace(typ.String(), ".", "_", -1)
+	}
+
+	// reserve the name first, the type may reference itself.
+	s.names[typ] = name
+	s.components[name] = nil
+	s.components[name] = s.object(typ)
+
+	return name
+}
+
+// object returns the schema of the struct type "typ", its fields are named like the encoding/json does.
+func (s *openAPISchemas) object(typ reflect.Type) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+	s.addFields(schema, typ)
+	if len(schema.Properties) == 0 {
+		schema.Properties = nil
+	}
+
+	return schema
+}
+
+func (s *openAPISchemas) addFields(schema *JSONSchema, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if idx := strings.IndexByte(tag, ','); idx != -1 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			// the fields of an embedded struct are promoted.
+			s.addFields(schema, fieldType)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			// unexported.
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := s.of(field.Type)
+		if strings.Contains(opts, "string") && fieldSchema.Ref == "" {
+			fieldSchema = &JSONSchema{Type: "string"}
+		}
+		schema.Properties[name] = fieldSchema
+
+		// the "omitempty" and the pointer fields may be missing or null.
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package muxie
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type openAPITestAddress struct {
+	City string `json:"city"`
+}
+
+type openAPITestUser struct {
+	ID        int64               `json:"id"`
+	Name      string              `json:"name"`
+	Email     string              `json:"email,omitempty"`
+	Friends   []*openAPITestUser  `json:"friends,omitempty"`
+	Address   *openAPITestAddress `json:"address"`
+	CreatedAt time.Time           `json:"created_at"`
+	Secret    string              `json:"-"`
+	internal  string
+}
+
+func TestMuxOpenAPI(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	mux := NewMux()
+	mux.HandleFunc("/", noop, WithTag("index"))
+
+	users := mux.Of("/users")
+	users.Get("/:id<int>", noop, WithTag("user"), WithOpenAPI(OpenAPIOperation{
+		Method:   http.MethodGet,
+		Summary:  "Get a user",
+		Tags:     []string{"users"},
+		Response: openAPITestUser{},
+	}))
+	users.Put("/:id<int>", noop, WithOpenAPI(OpenAPIOperation{
+		Method:   http.MethodPut,
+		Summary:  "Update a user",
+		Request:  &openAPITestUser{},
+		Response: openAPITestUser{},
+	}))
+	users.Post("/", noop, WithOpenAPI(OpenAPIOperation{Request: openAPITestUser{}, ResponseStatus: http.StatusCreated}))
+	mux.Handle("/files/*path", Methods().HandleFunc("GET, DELETE", noop))
+	mux.HandleFunc("/images/img+:name", noop)
+	mux.HandleFunc("/internal", noop, WithOpenAPI(OpenAPIOperation{Hidden: true}))
+	mux.HandleOpenAPI("/openapi.json", OpenAPIInfo{Title: "Users API", Version: "2.0.0"})
+
+	doc := mux.OpenAPI(OpenAPIInfo{Title: "Users API", Version: "2.0.0"})
+
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	expectedPaths := []string{"/", "/files/{path}", "/images/img{name}", "/users/", "/users/{id}"}
+	sort.Strings(paths)
+	if !reflect.DeepEqual(expectedPaths, paths) {
+		t.Fatalf("expected paths: %v but got: %v", expectedPaths, paths)
+	}
+
+	if op := doc.Paths["/"]["get"]; op == nil || op.OperationID != "index" || op.Responses["200"].Description != "OK" {
+		t.Fatalf("unexpected index operation: %#v", op)
+	}
+
+	if item := doc.Paths["/files/{path}"]; len(item) != 2 || item["get"] == nil || item["delete"] == nil ||
+		item["get"].Parameters[0].Description == "" {
+		t.Fatalf("expected the get and delete operations of the wildcard route but got: %#v", item)
+	}
+
+	if op := doc.Paths["/users/"]["post"]; op == nil || op.RequestBody == nil || op.Responses["201"].Description != "Created" {
+		t.Fatalf("unexpected create user operation: %#v", op)
+	}
+
+	getUser := doc.Paths["/users/{id}"]["get"]
+	if getUser == nil || getUser.Summary != "Get a user" || getUser.OperationID != "" || !reflect.DeepEqual(getUser.Tags, []string{"users"}) {
+		t.Fatalf("unexpected get user operation: %#v", getUser)
+	}
+
+	if expected, got := (OpenAPIParameter{Name: "id", In: "path", Required: true, Schema: &JSONSchema{Type: "integer", Format: "int64"}}),
+		getUser.Parameters[0]; !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected parameter: %#v but got: %#v", expected, got)
+	}
+
+	if ref := getUser.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/openAPITestUser" {
+		t.Fatalf("unexpected response schema reference: %s", ref)
+	}
+
+	if op := doc.Paths["/users/{id}"]["put"]; op == nil || op.Summary != "Update a user" || op.RequestBody == nil {
+		t.Fatalf("unexpected update user operation: %#v", op)
+	}
+
+	user := doc.Components.Schemas["openAPITestUser"]
+	if user == nil {
+		t.Fatalf("expected the user schema")
+	}
+
+	if expected := []string{"id", "name", "created_at"}; !reflect.DeepEqual(expected, user.Required) {
+		t.Fatalf("expected required fields: %v but got: %v", expected, user.Required)
+	}
+
+	expectedProperties := map[string]*JSONSchema{
+		"id":         {Type: "integer", Format: "int64"},
+		"name":       {Type: "string"},
+		"email":      {Type: "string"},
+		"friends":    {Type: "array", Items: &JSONSchema{Ref: "#/components/schemas/openAPITestUser"}},
+		"address":    {Ref: "#/components/schemas/openAPITestAddress"},
+		"created_at": {Type: "string", Format: "date-time"},
+	}
+	if !reflect.DeepEqual(expectedProperties, user.Properties) {
+		b, _ := json.Marshal(user.Properties)
+		t.Fatalf("unexpected user properties: %s", b)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var served OpenAPIDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatal(err)
+	}
+
+	if served.OpenAPI != OpenAPIVersion || served.Info.Title != "Users API" || len(served.Paths) != len(expectedPaths) {
+		t.Fatalf("unexpected served document: %s", rec.Body.String())
+	}
+}
+
+func TestOpenAPIDocumentYAML(t *testing.T) {
+	mux := NewMux()
+	mux.HandleMethodFunc(http.MethodGet, "/users/:id", func(w http.ResponseWriter, r *http.Request) {},
+		WithOpenAPI(OpenAPIOperation{Summary: "Get a user: by id", Response: []string{}}))
+	mux.HandleOpenAPI("/openapi.yaml", OpenAPIInfo{})
+
+	expected := `openapi: "3.0.3"
+info:
+  title: API
+  version: "1.0.0"
+paths:
+  "/users/{id}":
+    get:
+      summary: "Get a user: by id"
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  type: string
+`
+
+	testHandler(t, mux, http.MethodGet, "/openapi.yaml").statusCode(http.StatusOK).bodyEq(expected)
+}
@@ -0,0 +1,171 @@
+package muxie
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// OptionalParamEnd is the character, as a string, which marks the path segment before it as optional, i.e "/reports/:year?".
+	OptionalParamEnd = "?"
+	// OptionalStart is the character, as a string, which starts an optional part of a path pattern, i.e "/docs[/:lang]".
+	// Optional parts can be nested, i.e "/docs[/:lang[/:version]]".
+	OptionalStart = "["
+	// OptionalEnd is the character, as a string, which ends an optional part of a path pattern.
+	OptionalEnd = "]"
+)
+
+// optionalPart is a literal text or an optional part of a path pattern, see `parseOptional`.
+type optionalPart struct {
+	literal string
+	// the parts of an optional part, nil for a literal text.
+	optional []optionalPart
+}
+
+// expandOptional returns the path patterns that the optional parts of the "pattern" expand to,
+// the one without any of the optional parts first and the one with all of them last,
+// i.e "/reports" and "/reports/:year" for the "/reports/:year?".
+// A pattern without optional parts expands to itself.
+func expandOptional(pattern string) ([]string, error) {
+	parts, err := parseOptional(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 0 || (len(parts) == 1 && parts[0].optional == nil) {
+		return []string{pattern}, nil
+	}
+
+	var (
+		patterns []string
+		seen     = make(map[string]struct{})
+	)
+	for _, p := range expandOptionalParts(parts) {
+		if p == "" {
+			p = pathSep
+		}
+
+		if p[0] != pathSepB {
+			return nil, fmt.Errorf("pattern %q expands to %q which does not start with a %q", pattern, p, pathSep)
+		}
+
+		if _, ok := seen[p]; !ok {
+			seen[p] = struct{}{}
+			patterns = append(patterns, p)
+		}
+	}
+
+	return patterns, nil
+}
+
+func expandOptionalParts(parts []optionalPart) []string {
+	patterns := []string{""}
+	for _, part := range parts {
+		if part.optional == nil {
+			for i := range patterns {
+				patterns[i] += part.literal
+			}
+			continue
+		}
+
+		inner := expandOptionalParts(part.optional)
+		expanded := make([]string, 0, len(patterns)*(len(inner)+1))
+		// without the optional part first.
+		expanded = append(expanded, patterns...)
+		for _, s := range inner {
+			for _, p := range patterns {
+				expanded = append(expanded, p+s)
+			}
+		}
+		patterns = expanded
+	}
+
+	return patterns
+}
+
+// parseOptional splits the "pattern" to its literal text and its optional parts,
+// a path segment that ends with "?" is an optional part too. The constraints of the parameters are not parsed,
+// so their "[", "]" and "?" are not taken into account, i.e "/files/:name<regex([a-z]+)>?".
+func parseOptional(pattern string) ([]optionalPart, error) {
+	var (
+		stack   [][]optionalPart
+		parts   []optionalPart
+		literal strings.Builder
+	)
+
+	addLiteral := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, optionalPart{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case ParamConstraintStart[0]:
+			end := skipMixedConstraint(pattern, i)
+			literal.WriteString(pattern[i:end])
+			i = end - 1
+		case OptionalStart[0]:
+			addLiteral()
+			stack = append(stack, parts)
+			parts = nil
+		case OptionalEnd[0]:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("pattern %q: unexpected %q", pattern, OptionalEnd)
+			}
+
+			addLiteral()
+			if len(parts) == 0 {
+				return nil, fmt.Errorf("pattern %q: empty optional part", pattern)
+			}
+
+			optional := parts
+			parts = append(stack[len(stack)-1], optionalPart{optional: optional})
+			stack = stack[:len(stack)-1]
+		case OptionalParamEnd[0]:
+			if next := i + 1; next < len(pattern) && pattern[next] != pathSepB && pattern[next] != OptionalEnd[0] {
+				return nil, fmt.Errorf("pattern %q: %q should end a path segment", pattern, OptionalParamEnd)
+			}
+
+			// the path segment before the "?" should be part of the current literal text.
+			s := literal.String()
+			idx := strings.LastIndexByte(s, pathSepB)
+			if idx == -1 || idx == len(s)-1 {
+				return nil, fmt.Errorf("pattern %q: %q should follow a path segment", pattern, OptionalParamEnd)
+			}
+
+			literal.Reset()
+			literal.WriteString(s[:idx])
+			addLiteral()
+			parts = append(parts, optionalPart{optional: []optionalPart{{literal: s[idx:]}}})
+		default:
+			literal.WriteByte(c)
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("pattern %q: missing %q", pattern, OptionalEnd)
+	}
+	addLiteral()
+
+	return parts, nil
+}
+
+// insertPatterns returns the path patterns that the "pattern" expands to, see `expandOptional`.
+// It panics on an invalid pattern, the "method" is the name of the Trie's method for the panic message.
+func insertPatterns(method, pattern string) []string {
+	patterns, err := expandOptional(pattern)
+	if err != nil {
+		panic("muxie/trie#" + method + ": " + err.Error())
+	}
+
+	return patterns
+}
+
+// setOptional keeps the original "pattern" with its optional parts as the key of this node,
+// which is the node of one of its expansions.
+func (n *NodeOf[T]) setOptional(pattern string) {
+	n.key = pattern
+	n.optional = true
+}
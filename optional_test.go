@@ -0,0 +1,175 @@
+package muxie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestExpandOptional(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []string
+		err      bool
+	}{
+		{"/reports/:year?", []string{"/reports", "/reports/:year"}, false},
+		{"/docs[/:lang]", []string{"/docs", "/docs/:lang"}, false},
+		{"/docs[/:lang[/:version]]", []string{"/docs", "/docs/:lang", "/docs/:lang/:version"}, false},
+		{"/a/:x?/:y?", []string{"/a", "/a/:x", "/a/:y", "/a/:x/:y"}, false},
+		{"/files/:name[.:ext]", []string{"/files/:name", "/files/:name.:ext"}, false},
+		{"/:lang?", []string{"/", "/:lang"}, false},
+		{"/files/:name<regex([a-z]+)>", []string{"/files/:name<regex([a-z]+)>"}, false},
+		{"/files/:name<regex(a?)>?", []string{"/files", "/files/:name<regex(a?)>"}, false},
+		{"/static", []string{"/static"}, false},
+		{"/docs[/:lang", nil, true},
+		{"/docs]", nil, true},
+		{"/docs[]", nil, true},
+		{"/a?b", nil, true},
+		{"[a]/b", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := expandOptional(tt.pattern)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("%s: expected an error but got: %v", tt.pattern, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", tt.pattern, err)
+		}
+
+		if !reflect.DeepEqual(tt.expected, got) {
+			t.Fatalf("%s: expected: %v but got: %v", tt.pattern, tt.expected, got)
+		}
+	}
+}
+
+func TestTrieOptional(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/reports/:year?", WithTag("reports"))
+	tree.Insert("/docs[/:lang[/:version]]", WithTag("docs"))
+
+	tests := []struct {
+		path   string
+		tag    string
+		params []ParamEntry
+	}{
+		{"/reports", "reports", nil},
+		{"/reports/2024", "reports", []ParamEntry{{"year", "2024"}}},
+		{"/docs", "docs", nil},
+		{"/docs/en", "docs", []ParamEntry{{"lang", "en"}}},
+		{"/docs/en/v1", "docs", []ParamEntry{{"lang", "en"}, {"version", "v1"}}},
+		{"/docs/en/v1/more", "", nil},
+	}
+
+	for _, tt := range tests {
+		params := new(Writer)
+		n := tree.Search(tt.path, params)
+		if tt.tag == "" {
+			if n != nil {
+				t.Fatalf("%s: expected to not be found but found: %s", tt.path, n.String())
+			}
+			continue
+		}
+
+		if n == nil || n.Tag != tt.tag {
+			t.Fatalf("%s: expected to be found by: %s but got: %v", tt.path, tt.tag, n)
+		}
+
+		if got := params.GetAll(); !reflect.DeepEqual(tt.params, got) && (len(tt.params) != 0 || len(got) != 0) {
+			t.Fatalf("%s: expected params: %v but got: %v", tt.path, tt.params, got)
+		}
+	}
+
+	reverseTests := []struct {
+		tag      string
+		params   map[string]string
+		expected string
+		err      bool
+	}{
+		{"reports", nil, "/reports", false},
+		{"reports", map[string]string{"year": "2024"}, "/reports/2024", false},
+		{"reports", map[string]string{"month": "1"}, "", true},
+		{"docs", map[string]string{"lang": "en"}, "/docs/en", false},
+		{"docs", map[string]string{"lang": "en", "version": "v1"}, "/docs/en/v1", false},
+		{"docs", map[string]string{"version": "v1"}, "", true},
+	}
+
+	for _, tt := range reverseTests {
+		got, err := tree.Reverse(tt.tag, tt.params)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("%s %v: expected an error but got: %s", tt.tag, tt.params, got)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.expected {
+			t.Fatalf("%s %v: expected: %s but got: %s (%v)", tt.tag, tt.params, tt.expected, got, err)
+		}
+	}
+
+	if !tree.Delete("/reports/:year?") {
+		t.Fatalf("expected the optional pattern to be deleted")
+	}
+	if tree.Search("/reports", new(Writer)) != nil || tree.Search("/reports/2024", new(Writer)) != nil {
+		t.Fatalf("expected all the expansions to be deleted")
+	}
+}
+
+func TestTrieOptionalStrict(t *testing.T) {
+	tree := NewTrie().Strict()
+	tree.Insert("/reports", WithTag("reports"))
+
+	if err := tree.TryInsert("/reports/:year?"); err == nil {
+		t.Fatalf("expected a duplicate route error for the expansion without the optional segment")
+	}
+	if tree.Search("/reports/2024", new(Writer)) != nil {
+		t.Fatalf("expected none of the expansions to be inserted")
+	}
+
+	if err := tree.TryInsert("/reports[/:year"); err == nil {
+		t.Fatalf("expected an error for an invalid optional pattern")
+	}
+}
+
+func TestMuxOptional(t *testing.T) {
+	mux := NewMux()
+	v1 := mux.Of("/v1")
+	v1.HandleFunc("/reports/:year?", func(w http.ResponseWriter, r *http.Request) {
+		year, ok := LookupParam(w, "year")
+		if !ok {
+			year = "all"
+		}
+		w.Write([]byte(year))
+	}, WithTag("reports"))
+
+	for path, expected := range map[string]string{"/v1/reports": "all", "/v1/reports/2024": "2024"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rec.Body.String(); got != expected {
+			t.Fatalf("%s: expected body: %s but got: %s", path, expected, got)
+		}
+	}
+
+	routes := mux.ListRoutes()
+	if len(routes) != 1 || routes[0].Pattern != "/v1/reports/:year?" {
+		t.Fatalf("expected the optional pattern to be listed once but got: %v", routes)
+	}
+	if expected := []RouteParam{{Name: "year", Kind: NamedParam, Optional: true}}; !reflect.DeepEqual(expected, routes[0].Params) {
+		t.Fatalf("expected params: %v but got: %v", expected, routes[0].Params)
+	}
+
+	doc := mux.OpenAPI(OpenAPIInfo{})
+	if len(doc.Paths) != 2 || doc.Paths["/v1/reports"] == nil || doc.Paths["/v1/reports/{year}"] == nil {
+		t.Fatalf("expected a path per expansion but got: %v", doc.Paths)
+	}
+
+	if got, err := mux.URL("reports", "year", "2024"); err != nil || got != "/v1/reports/2024" {
+		t.Fatalf("expected the reversed path but got: %s (%v)", got, err)
+	}
+}
@@ -0,0 +1,193 @@
+package muxie
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// ParamConstraintStart is the character, as a string, which starts the constraint of a named parameter,
+	// i.e "/users/:id<int>" or "/files/:name<regex([a-z]+\.txt)>".
+	ParamConstraintStart = "<"
+	// ParamConstraintEnd is the character, as a string, which ends the constraint of a named parameter.
+	ParamConstraintEnd = ">"
+)
+
+// ParamConstraint reports whether a path segment's value is accepted by a named parameter.
+// See `RegisterParamConstraint`.
+type ParamConstraint func(value string) bool
+
+// ParamConstraintFactory builds a `ParamConstraint` based on its argument,
+// the argument is the text inside the parentheses of the constraint, i.e
+// "[a-z]+" for the "regex([a-z]+)" and it is empty if the constraint has no parentheses, i.e "int".
+type ParamConstraintFactory func(arg string) (ParamConstraint, error)
+
+var (
+	paramConstraintsMu sync.RWMutex
+	paramConstraints   = map[string]ParamConstraintFactory{
+		"int":   staticParamConstraint("int", isIntParam),
+		"uint":  staticParamConstraint("uint", isUintParam),
+		"alpha": staticParamConstraint("alpha", isAlphaParam),
+		"uuid":  staticParamConstraint("uuid", isUUIDParam),
+		"regex": regexParamConstraint,
+	}
+)
+
+// RegisterParamConstraint adds or replaces a named parameter constraint which can be used
+// by path patterns registered afterwards, i.e:
+//
+//	muxie.RegisterParamConstraint("even", func(arg string) (muxie.ParamConstraint, error) {
+//	    return func(value string) bool {
+//	        n, err := strconv.Atoi(value)
+//	        return err == nil && n%2 == 0
+//	    }, nil
+//	})
+//	mux.HandleFunc("/numbers/:n<even>", evenHandler)
+//
+// Built-in constraints are: "int", "uint", "alpha", "uuid" and "regex(expr)".
+func RegisterParamConstraint(name string, factory ParamConstraintFactory) {
+	if name == "" || factory == nil {
+		panic("muxie/RegisterParamConstraint: empty name or factory")
+	}
+
+	paramConstraintsMu.Lock()
+	paramConstraints[name] = factory
+	paramConstraintsMu.Unlock()
+}
+
+// parseParamConstraint splits a named parameter segment (without the ":")
+// to its parameter name and its constraint, if any.
+// The returned "key" is the constraint as it is written inside the "<>", i.e "int" or "regex([a-z]+)".
+func parseParamConstraint(s string) (name, key string, constraint ParamConstraint, err error) {
+	name, key = splitParamConstraint(s)
+	if key == "" {
+		return name, "", nil, nil
+	}
+
+	constraintName, arg := key, ""
+	if p := strings.IndexByte(key, '('); p != -1 && key[len(key)-1] == ')' {
+		constraintName, arg = key[:p], key[p+1:len(key)-1]
+	}
+
+	paramConstraintsMu.RLock()
+	factory, ok := paramConstraints[constraintName]
+	paramConstraintsMu.RUnlock()
+	if !ok {
+		return "", "", nil, fmt.Errorf("unknown parameter constraint %q", constraintName)
+	}
+
+	constraint, err = factory(arg)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("parameter constraint %q: %w", key, err)
+	}
+
+	return
+}
+
+// splitParamConstraint splits a named parameter segment (without the ":")
+// to its parameter name and the text inside the "<>", if any, without validating the constraint.
+func splitParamConstraint(s string) (name, key string) {
+	idx := strings.Index(s, ParamConstraintStart)
+	if idx == -1 || !strings.HasSuffix(s, ParamConstraintEnd) {
+		return s, ""
+	}
+
+	return s[:idx], s[idx+1 : len(s)-1]
+}
+
+func staticParamConstraint(name string, constraint ParamConstraint) ParamConstraintFactory {
+	return func(arg string) (ParamConstraint, error) {
+		if arg != "" {
+			return nil, fmt.Errorf("%s does not accept an argument", name)
+		}
+
+		return constraint, nil
+	}
+}
+
+func regexParamConstraint(arg string) (ParamConstraint, error) {
+	// match the whole segment, not just a part of it.
+	rx, err := regexp.Compile("^(?:" + arg + ")$")
+	if err != nil {
+		return nil, err
+	}
+
+	return rx.MatchString, nil
+}
+
+// isIntParam checks the digits first, the strconv allocates its errors and the constraints run on each search.
+func isIntParam(value string) bool {
+	digits := value
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+
+	if !isDigits(digits) {
+		return false
+	}
+
+	_, err := strconv.ParseInt(value, 10, 64)
+	return err == nil
+}
+
+func isUintParam(value string) bool {
+	if !isDigits(value) {
+		return false
+	}
+
+	_, err := strconv.ParseUint(value, 10, 64)
+	return err == nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAlphaParam(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	for i := 0; i < len(value); i++ {
+		if c := value[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isUUIDParam(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,9 @@
 package muxie
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 // ParamStore should be completed by http.ResponseWriter to support dynamic path parameters.
 // See the `Writer` type for more.
@@ -19,20 +22,38 @@ type ParamStore interface {
 // then the `GetParam("name")` will return the value of "kataras".
 // If not associated value with that key is found then it will return an empty string.
 //
-// The function will do its job only if the given "w" http.ResponseWriter interface is a `ParamStore`.
+// The function will do its job only if the given "w" http.ResponseWriter interface is a `ParamStore`
+// or it wraps one, see `ParamStoreOf`. Use the `RequestParam` when the response writer
+// may be replaced by a middleware which does not unwrap to the original one.
 func GetParam(w http.ResponseWriter, key string) string {
-	if store, ok := w.(ParamStore); ok {
+	if store := ParamStoreOf(w); store != nil {
 		return store.Get(key)
 	}
 
 	return ""
 }
 
+// LookupParam is like the `GetParam` but it reports whether the parameter is set,
+// i.e an optional parameter of the "/reports/:year?" is missing for the "/reports" path
+// while the `GetParam` returns an empty string for both a missing and an empty parameter.
+func LookupParam(w http.ResponseWriter, key string) (string, bool) {
+	if store := ParamStoreOf(w); store != nil {
+		for _, p := range store.GetAll() {
+			if p.Key == key {
+				return p.Value, true
+			}
+		}
+	}
+
+	return "", false
+}
+
 // GetParams returns all the available parameters based on the "w" http.ResponseWriter which should be a ParamStore.
 //
-// The function will do its job only if the given "w" http.ResponseWriter interface is a `ParamStore`.
+// The function will do its job only if the given "w" http.ResponseWriter interface is a `ParamStore`
+// or it wraps one, see `ParamStoreOf`.
 func GetParams(w http.ResponseWriter) []ParamEntry {
-	if store, ok := w.(ParamStore); ok {
+	if store := ParamStoreOf(w); store != nil {
 		return store.GetAll()
 	}
 
@@ -43,7 +64,7 @@ func GetParams(w http.ResponseWriter) []ParamEntry {
 // This is not commonly used by the end-developers,
 // unless sharing values(string messages only) between handlers is absolutely necessary.
 func SetParam(w http.ResponseWriter, key, value string) bool {
-	if store, ok := w.(ParamStore); ok {
+	if store := ParamStoreOf(w); store != nil {
 		store.Set(key, value)
 		return true
 	}
@@ -51,6 +72,109 @@ func SetParam(w http.ResponseWriter, key, value string) bool {
 	return false
 }
 
+// ParamStoreOf returns the `ParamStore` of the "w" http.ResponseWriter.
+// If "w" is not a `ParamStore` itself then its chain of response writers
+// is walked through their `Unwrap() http.ResponseWriter` method,
+// the same method that the `http.ResponseController` expects, until a `ParamStore` is found.
+// It returns nil if none of them is a `ParamStore`.
+func ParamStoreOf(w http.ResponseWriter) ParamStore {
+	for w != nil {
+		if store, ok := w.(ParamStore); ok {
+			return store
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+
+	return nil
+}
+
+// WriterOf returns the `Writer` of the "w" http.ResponseWriter, the response writers of its chain
+// are walked through their `Unwrap() http.ResponseWriter` method, like the `ParamStoreOf`.
+// It returns nil if none of them is a `Writer`.
+//
+// Use it instead of the `w.(*muxie.Writer)` type assertion, see `Writer`.
+func WriterOf(w http.ResponseWriter) *Writer {
+	for w != nil {
+		if pw, ok := w.(interface{ paramsWriter() *Writer }); ok {
+			return pw.paramsWriter()
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+
+	return nil
+}
+
+// ParamsMode describes where the `Mux` stores the path parameters of the matched route,
+// besides the `Writer`. See `Mux#ParamsMode`.
+type ParamsMode uint8
+
+const (
+	// ParamsInWriter stores the parameters only to the `Writer` that the handlers receive,
+	// this is the default mode and it does not allocate.
+	ParamsInWriter ParamsMode = 0
+	// ParamsInContext stores the `ParamStore` to the request's context as well,
+	// so the parameters can be retrieved through the `RequestParam` and `RequestParams`
+	// even if a middleware replaced the response writer.
+	// Like the `Writer`, the stored `ParamStore` is valid only while the route's handler is running.
+	ParamsInContext ParamsMode = 1
+	// ParamsInPathValue sets the parameters to the request through its `SetPathValue` as well,
+	// so they can be retrieved through the `Request.PathValue` and the `RequestParam`.
+	// It requires Go 1.22 or later, it does nothing on previous versions.
+	ParamsInPathValue ParamsMode = 2
+)
+
+type paramsContextKey struct{}
+
+// WithParamStore returns a copy of the "r" http.Request which holds the "store" `ParamStore` in its context.
+// The `Mux` calls it when its `ParamsMode` contains the `ParamsInContext`.
+func WithParamStore(r *http.Request, store ParamStore) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), paramsContextKey{}, store))
+}
+
+// RequestParamStore returns the `ParamStore` which is stored in the request's context, if any.
+// See `WithParamStore`.
+func RequestParamStore(r *http.Request) ParamStore {
+	store, _ := r.Context().Value(paramsContextKey{}).(ParamStore)
+	return store
+}
+
+// RequestParam returns the path parameter value based on its key, like the `GetParam` does,
+// but it reads the parameter from the request itself instead of the response writer.
+// It looks the `ParamStore` of the request's context first and then the request's `PathValue`.
+//
+// The function will do its job only if the `Mux#ParamsMode` contains the `ParamsInContext`
+// or the `ParamsInPathValue`, or the route was registered through `Mux#HandleStd`.
+func RequestParam(r *http.Request, key string) string {
+	if store := RequestParamStore(r); store != nil {
+		if value := store.Get(key); value != "" {
+			return value
+		}
+	}
+
+	return pathValue(r, key)
+}
+
+// RequestParams returns all the available parameters based on the request's context.
+//
+// The function will do its job only if the `Mux#ParamsMode` contains the `ParamsInContext`.
+func RequestParams(r *http.Request) []ParamEntry {
+	if store := RequestParamStore(r); store != nil {
+		return store.GetAll()
+	}
+
+	return nil
+}
+
 // ParamEntry holds the Key and the Value of a named path parameter.
 type ParamEntry struct {
 	Key   string
@@ -58,11 +182,22 @@ type ParamEntry struct {
 }
 
 // Writer is the muxie's specific ResponseWriter to hold the path parameters.
-// Usage: use this to cast a handler's `http.ResponseWriter` and pass it as an embedded parameter to custom response writer
-// that will be passed to the next handler in the chain.
+// Usage: a custom response writer which is passed to the next handler in the chain
+// can embed the handler's `http.ResponseWriter` and implement the `Unwrap() http.ResponseWriter`
+// so the parameters are still reachable, see `ParamStoreOf`.
+//
+// The handlers receive the `Writer` combined with exactly the optional interfaces of the underlying response writer,
+// i.e `http.Flusher`, `http.Hijacker`, `http.Pusher` and `io.ReaderFrom`, so type assertions like `w.(http.Flusher)`
+// work as expected.
+//
+// Breaking change: the response writer of the handlers is a `*Writer` only when the underlying one
+// has none of these interfaces, the `w.(*muxie.Writer)` type assertion panics on a `net/http` server,
+// use the `WriterOf` or the `ParamStoreOf` instead.
 type Writer struct {
 	http.ResponseWriter
 	params []ParamEntry
+	// the optional interfaces of the ResponseWriter, see `writerInterfaces`.
+	interfaces uint8
 }
 
 var _ ParamStore = (*Writer)(nil)
@@ -102,7 +237,14 @@ func (pw *Writer) GetAll() []ParamEntry {
 	return pw.params
 }
 
+// Unwrap returns the underlying response writer,
+// it is used by the `ParamStoreOf` and the `http.ResponseController`.
+func (pw *Writer) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
 func (pw *Writer) reset(w http.ResponseWriter) {
 	pw.ResponseWriter = w
+	pw.interfaces = writerInterfaces(w)
 	pw.params = pw.params[0:0]
 }
@@ -0,0 +1,34 @@
+//go:build go1.20
+// +build go1.20
+
+package muxie
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriterResponseController(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		// SetWriteDeadline is not implemented by the Writer,
+		// the ResponseController reaches the net/http's one through the Unwrap method.
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, GetParam(w, "id"))
+		if err := rc.Flush(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	testServerBody(t, srv.Client(), srv.URL+"/users/42", "42")
+}
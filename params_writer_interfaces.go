@@ -0,0 +1,256 @@
+package muxie
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// The optional interfaces of an http.ResponseWriter that the `Writer` forwards to the underlying writer.
+const (
+	writerFlusher uint8 = 1 << iota
+	writerHijacker
+	writerPusher
+	writerReaderFrom
+)
+
+func writerInterfaces(w http.ResponseWriter) (flags uint8) {
+	if _, ok := w.(http.Flusher); ok {
+		flags |= writerFlusher
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		flags |= writerHijacker
+	}
+	if _, ok := w.(http.Pusher); ok {
+		flags |= writerPusher
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		flags |= writerReaderFrom
+	}
+
+	return
+}
+
+// writer returns the response writer that the route handlers receive:
+// the `Writer` itself combined with exactly the optional interfaces
+// (`http.Flusher`, `http.Hijacker`, `http.Pusher` and `io.ReaderFrom`) of the underlying response writer.
+// The combinations hold just a pointer to the `Writer`,
+// so converting them to an http.ResponseWriter does not allocate.
+func (pw *Writer) writer() http.ResponseWriter {
+	switch pw.interfaces {
+	case writerFlusher:
+		return flushWriter{pw}
+	case writerHijacker:
+		return hijackWriter{pw}
+	case writerFlusher | writerHijacker:
+		return flushHijackWriter{pw}
+	case writerPusher:
+		return pushWriter{pw}
+	case writerFlusher | writerPusher:
+		return flushPushWriter{pw}
+	case writerHijacker | writerPusher:
+		return hijackPushWriter{pw}
+	case writerFlusher | writerHijacker | writerPusher:
+		return flushHijackPushWriter{pw}
+	case writerReaderFrom:
+		return readFromWriter{pw}
+	case writerFlusher | writerReaderFrom:
+		return flushReadFromWriter{pw}
+	case writerHijacker | writerReaderFrom:
+		return hijackReadFromWriter{pw}
+	case writerFlusher | writerHijacker | writerReaderFrom:
+		return flushHijackReadFromWriter{pw}
+	case writerPusher | writerReaderFrom:
+		return pushReadFromWriter{pw}
+	case writerFlusher | writerPusher | writerReaderFrom:
+		return flushPushReadFromWriter{pw}
+	case writerHijacker | writerPusher | writerReaderFrom:
+		return hijackPushReadFromWriter{pw}
+	case writerFlusher | writerHijacker | writerPusher | writerReaderFrom:
+		return flushHijackPushReadFromWriter{pw}
+	default:
+		return pw
+	}
+}
+
+// paramsWriter returns the `Writer` of its combinations, see `WriterOf`.
+func (pw *Writer) paramsWriter() *Writer {
+	return pw
+}
+
+func (pw *Writer) flush() {
+	pw.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (pw *Writer) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return pw.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+func (pw *Writer) push(target string, opts *http.PushOptions) error {
+	return pw.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+func (pw *Writer) readFrom(src io.Reader) (int64, error) {
+	return pw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+}
+
+// The combinations of the optional interfaces, see `Writer#writer`.
+
+type flushWriter struct{ *Writer }
+
+func (w flushWriter) Flush() {
+	w.flush()
+}
+
+type hijackWriter struct{ *Writer }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+type flushHijackWriter struct{ *Writer }
+
+func (w flushHijackWriter) Flush() {
+	w.flush()
+}
+
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+type pushWriter struct{ *Writer }
+
+func (w pushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type flushPushWriter struct{ *Writer }
+
+func (w flushPushWriter) Flush() {
+	w.flush()
+}
+
+func (w flushPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type hijackPushWriter struct{ *Writer }
+
+func (w hijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w hijackPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type flushHijackPushWriter struct{ *Writer }
+
+func (w flushHijackPushWriter) Flush() {
+	w.flush()
+}
+
+func (w flushHijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w flushHijackPushWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+type readFromWriter struct{ *Writer }
+
+func (w readFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+type flushReadFromWriter struct{ *Writer }
+
+func (w flushReadFromWriter) Flush() {
+	w.flush()
+}
+
+func (w flushReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+type hijackReadFromWriter struct{ *Writer }
+
+func (w hijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w hijackReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+type flushHijackReadFromWriter struct{ *Writer }
+
+func (w flushHijackReadFromWriter) Flush() {
+	w.flush()
+}
+
+func (w flushHijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w flushHijackReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+type pushReadFromWriter struct{ *Writer }
+
+func (w pushReadFromWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+func (w pushReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+type flushPushReadFromWriter struct{ *Writer }
+
+func (w flushPushReadFromWriter) Flush() {
+	w.flush()
+}
+
+func (w flushPushReadFromWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+func (w flushPushReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+type hijackPushReadFromWriter struct{ *Writer }
+
+func (w hijackPushReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w hijackPushReadFromWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+func (w hijackPushReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
+
+type flushHijackPushReadFromWriter struct{ *Writer }
+
+func (w flushHijackPushReadFromWriter) Flush() {
+	w.flush()
+}
+
+func (w flushHijackPushReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.hijack()
+}
+
+func (w flushHijackPushReadFromWriter) Push(target string, opts *http.PushOptions) error {
+	return w.push(target, opts)
+}
+
+func (w flushHijackPushReadFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.readFrom(src)
+}
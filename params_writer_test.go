@@ -2,7 +2,11 @@ package muxie
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -16,3 +20,183 @@ func TestGetParam(t *testing.T) {
 
 	testHandler(t, mux, http.MethodGet, "/hello/kataras").bodyEq("Hello kataras")
 }
+
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+type unwrapWriter struct {
+	http.ResponseWriter
+}
+
+func (w *unwrapWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestGetParamUnwrap(t *testing.T) {
+	mux := NewMux()
+	mux.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&unwrapWriter{&unwrapWriter{w}}, r)
+		})
+	})
+
+	mux.HandleFunc("/hello/:name", func(w http.ResponseWriter, r *http.Request) {
+		SetParam(w, "greeting", "Hello")
+		fmt.Fprintf(w, "%s %s %d", GetParam(w, "greeting"), GetParam(w, "name"), len(GetParams(w)))
+	})
+
+	testHandler(t, mux, http.MethodGet, "/hello/kataras").bodyEq("Hello kataras 2")
+}
+
+func TestWriterOf(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/hello/:name", func(w http.ResponseWriter, r *http.Request) {
+		pw := WriterOf(w)
+		if pw == nil {
+			http.Error(w, "no writer", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "Hello %s", pw.Get("name"))
+	})
+	mux.Of("/wrapped").Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&unwrapWriter{w}, r)
+		})
+	})
+	mux.Of("/wrapped").HandleFunc("/:name", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Hello %s", WriterOf(w).Get("name"))
+	})
+
+	// the response writer of a net/http server has optional interfaces,
+	// the handler receives a combination of the `Writer` with them.
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	expect(t, http.MethodGet, srv.URL+"/hello/kataras").statusCode(http.StatusOK).bodyEq("Hello kataras")
+	expect(t, http.MethodGet, srv.URL+"/wrapped/kataras").statusCode(http.StatusOK).bodyEq("Hello kataras")
+
+	if WriterOf(httptest.NewRecorder()) != nil {
+		t.Fatalf("expected nil for a response writer without a Writer")
+	}
+}
+
+func TestRequestParam(t *testing.T) {
+	mux := NewMux()
+	mux.ParamsMode = ParamsInContext
+	mux.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&plainWriter{w}, r)
+		})
+	})
+
+	mux.Of("/users").HandleFunc("/:id/files/*path", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%q:%s:%s:%d", GetParam(w, "id"),
+			RequestParam(r, "id"), RequestParam(r, "path"), len(RequestParams(r)))
+	})
+
+	testHandler(t, mux, http.MethodGet, "/users/42/files/docs/cv.pdf").bodyEq(`"":42:docs/cv.pdf:2`)
+}
+
+func writerInterfacesOf(w http.ResponseWriter) string {
+	var names []string
+	if _, ok := w.(http.Flusher); ok {
+		names = append(names, "Flusher")
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		names = append(names, "Hijacker")
+	}
+	if _, ok := w.(http.Pusher); ok {
+		names = append(names, "Pusher")
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		names = append(names, "ReaderFrom")
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestWriterInterfaces(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s:%s:%s", writerInterfacesOf(w), GetParam(w, "id"), r.Proto)
+	})
+
+	// httptest.ResponseRecorder is a Flusher only.
+	testHandler(t, mux, http.MethodGet, "/users/42").bodyEq("Flusher:42:HTTP/1.1")
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	testServerBody(t, srv.Client(), srv.URL+"/users/42", "Flusher,Hijacker,ReaderFrom:42:HTTP/1.1")
+
+	tlsSrv := httptest.NewUnstartedServer(mux)
+	tlsSrv.EnableHTTP2 = true
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+	testServerBody(t, tlsSrv.Client(), tlsSrv.URL+"/users/42", "Flusher,Pusher:42:HTTP/2.0")
+}
+
+func TestWriterPush(t *testing.T) {
+	// see _examples/12_push.
+	mux := NewMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		pusher, ok := w.(http.Pusher)
+		if !ok {
+			http.Error(w, "HTTP/2 push not available", http.StatusHTTPVersionNotSupported)
+			return
+		}
+
+		// the Go client disables server push,
+		// the error comes from the underlying HTTP/2 response writer.
+		if err := pusher.Push("/main.js", nil); err != http.ErrNotSupported {
+			http.Error(w, fmt.Sprintf("unexpected push error: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, "pushed")
+	})
+
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	testServerBody(t, srv.Client(), srv.URL, "pushed")
+}
+
+func TestWriterFlush(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/events/:topic", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", GetParam(w, "topic"))
+		w.(http.Flusher).Flush()
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events/news", nil))
+	if !rec.Flushed {
+		t.Fatalf("expected the response to be flushed")
+	}
+
+	if expected, got := "data: news\n\n", rec.Body.String(); expected != got {
+		t.Fatalf("expected body: %q but got: %q", expected, got)
+	}
+}
+
+func testServerBody(t *testing.T, client *http.Client, url, expected string) {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(b); expected != got {
+		t.Fatalf("%s: expected to receive '%s' but got '%s'", url, expected, got)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build go1.22
+// +build go1.22
+
+package muxie
+
+import "net/http"
+
+// setPathValues sets the path parameters to the request, so they are available through its `PathValue`.
+func setPathValues(r *http.Request, params []ParamEntry) {
+	for _, p := range params {
+		if p.Key != "" {
+			r.SetPathValue(p.Key, p.Value)
+		}
+	}
+}
+
+// pathValue returns the value of the request's path wildcard of that "key", if any.
+func pathValue(r *http.Request, key string) string {
+	return r.PathValue(key)
+}
@@ -0,0 +1,12 @@
+//go:build !go1.22
+// +build !go1.22
+
+package muxie
+
+import "net/http"
+
+// setPathValues does nothing, the `Request.SetPathValue` was introduced in Go 1.22.
+func setPathValues(r *http.Request, params []ParamEntry) {}
+
+// pathValue returns an empty string, the `Request.PathValue` was introduced in Go 1.22.
+func pathValue(r *http.Request, key string) string { return "" }
@@ -0,0 +1,30 @@
+//go:build go1.22
+// +build go1.22
+
+package muxie
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMuxHandleStdPathValue(t *testing.T) {
+	mux := NewMux()
+	mux.HandleStdFunc("GET /users/{id}/files/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s:%s", r.PathValue("id"), r.PathValue("path"))
+	})
+
+	testHandler(t, mux, http.MethodGet, "/users/42/files/docs/cv.pdf").statusCode(http.StatusOK).
+		bodyEq("42:docs/cv.pdf")
+}
+
+func TestMuxParamsInPathValue(t *testing.T) {
+	mux := NewMux()
+	mux.ParamsMode = ParamsInPathValue
+	mux.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s:%s", r.PathValue("id"), RequestParam(r, "id"))
+	})
+
+	testHandler(t, mux, http.MethodGet, "/users/42").bodyEq("42:42")
+}
@@ -47,5 +47,5 @@ type Host string
 // Match validates the host, implementing the `Matcher` interface.
 func (h Host) Match(r *http.Request) bool {
 	s := string(h)
-	return r.Host == s || (s[0] == '.' && strings.HasSuffix(r.Host, s)) || s == WildcardParamStart
+	return r.Host == s || (len(s) > 0 && s[0] == '.' && strings.HasSuffix(r.Host, s)) || s == WildcardParamStart
 }
@@ -0,0 +1,252 @@
+package muxie
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Reverse builds a path based on the pattern of the node that its `Tag` is equal to "tag",
+// the named parameters, wildcards, prefixed and suffixed parameters are filled by the "params".
+// Parameter values are escaped, a wildcard's value can contain slashes and
+// a constrained parameter's value should pass its constraint.
+//
+// Returns an error if there is no node with that "tag" or
+// if a parameter of the pattern is missing or the "params" contain a parameter
+// that the pattern has not.
+//
+// The optional parts of the pattern are built if the "params" contain their parameters,
+// i.e "/reports/:year?" builds "/reports" without parameters and "/reports/2024" with the "year" one.
+//
+// Usage:
+// trie.Insert("/users/:id/files/*file", muxie.WithTag("user_file"))
+// trie.Reverse("user_file", map[string]string{"id": "42", "file": "docs/cv.pdf"})
+// returns "/users/42/files/docs/cv.pdf".
+func (t *TrieOf[T]) Reverse(tag string, params map[string]string) (string, error) {
+	n := t.load().getTagged(tag)
+	if n == nil {
+		return "", fmt.Errorf("muxie: route %q not found", tag)
+	}
+
+	if n.optional {
+		return reverseOptional(n.key, params)
+	}
+
+	return reversePattern(n.key, params)
+}
+
+// reverseOptional builds the path of the expansion of the "pattern" which has exactly the parameters of the "params",
+// the one with the fewest optional parts if more than one has them.
+func reverseOptional(pattern string, params map[string]string) (string, error) {
+	patterns, err := expandOptional(pattern)
+	if err != nil {
+		return "", fmt.Errorf("muxie: route %q: %w", pattern, err)
+	}
+
+	for _, expanded := range patterns {
+		routeParams := segmentParams(expanded)
+		if len(routeParams) != len(params) {
+			continue
+		}
+
+		found := true
+		for _, p := range routeParams {
+			if _, ok := params[p.Name]; !ok {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return reversePattern(expanded, params)
+		}
+	}
+
+	// report the missing or the unexpected parameters of the expansion with all the optional parts.
+	return reversePattern(patterns[len(patterns)-1], params)
+}
+
+func (tree *trieTree[T]) getTagged(tag string) *NodeOf[T] {
+	tree.tagsOnce.Do(func() {
+		tree.tags = make(map[string]*NodeOf[T])
+		tree.root.walkEnd(func(n *NodeOf[T]) {
+			if n.Tag == "" {
+				return
+			}
+
+			// keep the same result for the same nodes if more than one has the same tag.
+			if existing, ok := tree.tags[n.Tag]; !ok || n.key < existing.key {
+				tree.tags[n.Tag] = n
+			}
+		})
+	})
+
+	return tree.tags[tag]
+}
+
+// walkEnd calls "fn" for this node and its children that are complete nodes.
+func (n *NodeOf[T]) walkEnd(fn func(*NodeOf[T])) {
+	if n.end {
+		fn(n)
+	}
+
+	n.children.each(func(_ string, child *NodeOf[T]) {
+		child.walkEnd(fn)
+	})
+}
+
+func reversePattern(pattern string, params map[string]string) (string, error) {
+	if pattern == pathSep {
+		if len(params) > 0 {
+			return "", fmt.Errorf("muxie: route %q: unexpected parameters", pattern)
+		}
+		return pattern, nil
+	}
+
+	var (
+		b    strings.Builder
+		used = make(map[string]struct{}, len(params))
+	)
+
+	param := func(name string) (string, error) {
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("muxie: route %q: missing parameter %q", pattern, name)
+		}
+
+		used[name] = struct{}{}
+		return value, nil
+	}
+
+	for _, s := range slowPathSplit(pattern) {
+		b.WriteString(pathSep)
+
+		switch c := s[0]; {
+		case isMixedParam(s):
+			segment, err := reverseMixedSegment(pattern, s, param)
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteString(segment)
+		case c == ParamStart[0]:
+			name, _, constraint, err := parseParamConstraint(s[1:])
+			if err != nil {
+				return "", fmt.Errorf("muxie: route %q: %w", pattern, err)
+			}
+
+			value, err := param(name)
+			if err != nil {
+				return "", err
+			}
+
+			if value == "" || (constraint != nil && !constraint(value)) {
+				return "", fmt.Errorf("muxie: route %q: invalid value %q for parameter %q", pattern, value, name)
+			}
+
+			b.WriteString(url.PathEscape(value))
+		case c == WildcardParamStart[0]:
+			value, err := param(s[1:])
+			if err != nil {
+				return "", err
+			}
+
+			for i, segment := range strings.Split(strings.TrimPrefix(value, pathSep), pathSep) {
+				if i > 0 {
+					b.WriteString(pathSep)
+				}
+				b.WriteString(url.PathEscape(segment))
+			}
+		case isPrefixParam(s):
+			indx := strings.Index(s, PrefixParamStart)
+			value, err := param(s[indx+len(PrefixParamStart):])
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteString(s[:indx])
+			b.WriteString(url.PathEscape(value))
+		case isSuffixParam(s):
+			indx := strings.Index(s, SuffixParamStart)
+			value, err := param(s[:indx])
+			if err != nil {
+				return "", err
+			}
+
+			b.WriteString(url.PathEscape(value))
+			b.WriteString(s[indx+len(SuffixParamStart):])
+		default:
+			b.WriteString(s)
+		}
+	}
+
+	if len(used) != len(params) {
+		var extra []string
+		for name := range params {
+			if _, ok := used[name]; !ok {
+				extra = append(extra, name)
+			}
+		}
+		sort.Strings(extra)
+
+		return "", fmt.Errorf("muxie: route %q: unexpected parameters %q", pattern, extra)
+	}
+
+	return b.String(), nil
+}
+
+// reverseMixedSegment builds the path segment "s" of the "pattern" with mixed parameters.
+// The values should match back to the same parameters, i.e the "name" of the ":name.:ext"
+// can not contain a dot because the search would split the value at it.
+func reverseMixedSegment(pattern, s string, param func(name string) (string, error)) (string, error) {
+	m, err := parseMixedSegment(s, false)
+	if err != nil {
+		return "", fmt.Errorf("muxie: route %q: %w", pattern, err)
+	}
+
+	var (
+		b      strings.Builder
+		values []string
+	)
+	for _, part := range m.parts {
+		if part.name == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+
+		value, err := param(part.name)
+		if err != nil {
+			return "", err
+		}
+
+		if value == "" || (part.constraint != nil && !part.constraint(value)) {
+			return "", fmt.Errorf("muxie: route %q: invalid value %q for parameter %q", pattern, value, part.name)
+		}
+
+		values = append(values, value)
+		b.WriteString(value)
+	}
+
+	matched, ok := m.match(b.String(), b.String(), nil)
+	for i, value := range values {
+		if !ok || matched[i] != value {
+			return "", fmt.Errorf("muxie: route %q: value %q for parameter %q does not match back to it", pattern, value, m.names()[i])
+		}
+	}
+
+	// escape the values but not the literal text.
+	b.Reset()
+	i := 0
+	for _, part := range m.parts {
+		if part.name == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+
+		b.WriteString(url.PathEscape(values[i]))
+		i++
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,82 @@
+package muxie
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTrieReverse(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/", WithTag("index"))
+	tree.Insert("/users/:id<int>", WithTag("user"))
+	tree.Insert("/users/:id/files/*file", WithTag("user_file"))
+	tree.Insert("/images/img+:name", WithTag("image"))
+	tree.Insert("/texts/name-:.txt", WithTag("text"))
+	tree.Insert("/untagged")
+
+	tests := []struct {
+		tag      string
+		params   map[string]string
+		expected string
+		err      bool
+	}{
+		{"index", nil, "/", false},
+		{"user", map[string]string{"id": "42"}, "/users/42", false},
+		{"user", map[string]string{"id": "kataras"}, "", true},
+		{"user", map[string]string{}, "", true},
+		{"user", map[string]string{"id": "42", "other": "value"}, "", true},
+		{"user_file", map[string]string{"id": "a b", "file": "docs/my cv.pdf"}, "/users/a%20b/files/docs/my%20cv.pdf", false},
+		{"user_file", map[string]string{"id": "a/b", "file": ""}, "/users/a%2Fb/files/", false},
+		{"image", map[string]string{"name": "_001.png"}, "/images/img_001.png", false},
+		{"text", map[string]string{"name": "notes"}, "/texts/notes.txt", false},
+		{"untagged", nil, "", true},
+	}
+
+	for i, tt := range tests {
+		got, err := tree.Reverse(tt.tag, tt.params)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("[%d] %s: expected an error but got: '%s'", i, tt.tag, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("[%d] %s: %v", i, tt.tag, err)
+		}
+
+		if got != tt.expected {
+			t.Fatalf("[%d] %s: expected: '%s' but got: '%s'", i, tt.tag, tt.expected, got)
+		}
+
+		if n := tree.Search(got, new(Writer)); n == nil || n.Tag != tt.tag {
+			t.Fatalf("[%d] %s: expected the path '%s' to be found by the same node", i, tt.tag, got)
+		}
+	}
+}
+
+func TestMuxURL(t *testing.T) {
+	mux := NewMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {}, WithTag("index"))
+
+	v1 := mux.Of("/v1")
+	v1.Handle("/users/:id", http.NotFoundHandler(), WithTag("user"))
+
+	if got, err := mux.URL("index"); err != nil || got != "/" {
+		t.Fatalf("expected: '/' but got: '%s' (%v)", got, err)
+	}
+
+	if got, err := mux.URL("user", "id", "42"); err != nil || got != "/v1/users/42" {
+		t.Fatalf("expected: '/v1/users/42' but got: '%s' (%v)", got, err)
+	}
+
+	if _, err := mux.URL("user", "id"); err == nil {
+		t.Fatalf("expected an error for odd number of parameters")
+	}
+
+	// new routes are visible to the next calls.
+	v1.Handle("/users/:id/friends", http.NotFoundHandler(), WithTag("friends"))
+	if got, err := mux.URL("friends", "id", "42"); err != nil || got != "/v1/users/42/friends" {
+		t.Fatalf("expected: '/v1/users/42/friends' but got: '%s' (%v)", got, err)
+	}
+}
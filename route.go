@@ -0,0 +1,267 @@
+package muxie
+
+import (
+	"sort"
+	"strings"
+)
+
+// ParamKind is the kind of a path parameter, see `RouteParam`.
+type ParamKind uint8
+
+const (
+	// NamedParam is a single path segment parameter, i.e ":name" or ":id<int>".
+	NamedParam ParamKind = iota
+	// WildcardParam is a parameter which matches the rest of the path, i.e "*file".
+	WildcardParam
+	// PrefixParam is a parameter that follows a static prefix in the same path segment, i.e "img+:name".
+	PrefixParam
+	// SuffixParam is a parameter that precedes a static suffix in the same path segment, i.e "name-:.txt".
+	SuffixParam
+	// MixedParam is one of the parameters of a path segment with mixed parameters and literal text, i.e ":name.:ext".
+	MixedParam
+)
+
+// String returns the name of the parameter kind, i.e "named".
+func (k ParamKind) String() string {
+	switch k {
+	case NamedParam:
+		return "named"
+	case WildcardParam:
+		return "wildcard"
+	case PrefixParam:
+		return "prefix"
+	case SuffixParam:
+		return "suffix"
+	case MixedParam:
+		return "mixed"
+	default:
+		return "unknown"
+	}
+}
+
+// RouteParam describes a path parameter of a `Route`.
+type RouteParam struct {
+	Name string
+	Kind ParamKind
+	// Constraint is the constraint of a named or mixed parameter as it is written inside the "<>", i.e "int", if any.
+	Constraint string
+	// Affix is the static prefix of a `PrefixParam` or the static suffix of a `SuffixParam`, i.e "img" or ".txt",
+	// for a `MixedParam` it is the whole path segment, i.e ":name.:ext".
+	Affix string
+	// Optional reports whether the parameter is part of an optional part of the pattern, i.e "/reports/:year?".
+	Optional bool
+}
+
+// Route describes a registered path pattern, see `Node#Route`, `Trie#Walk` and `Mux#ListRoutes`.
+type Route struct {
+	// Host is the host pattern of the route, i.e ":tenant.app.com", empty if the route matches any host.
+	// See `Mux#Handle`.
+	Host    string
+	Pattern string
+	Params  []RouteParam
+	Tag     string
+	Data    interface{}
+	// Methods are the HTTP methods that the route has specific handlers for, sorted,
+	// through `Mux#HandleMethod` or a `MethodHandler`,
+	// empty if the route handles all methods through a single handler.
+	Methods []string
+	// Conditions are the descriptions of the matchers of the route's conditional handlers, in order,
+	// i.e `Accept("application/vnd.x.v2+json")`, see `Mux#HandleWhen`.
+	Conditions []string
+	// Middlewares is the number of the `Mux#Use` middlewares that wrap the route's handlers.
+	Middlewares int
+}
+
+// Route returns the description of this node's path pattern,
+// it is an empty `Route` if this node is not a complete one, see `IsEnd`.
+func (n *NodeOf[T]) Route() Route {
+	if !n.end {
+		return Route{}
+	}
+
+	return Route{
+		Pattern:     n.key,
+		Params:      routeParams(n.key),
+		Tag:         n.Tag,
+		Data:        n.Data,
+		Methods:     n.routeMethods(),
+		Conditions:  n.routeConditions(),
+		Middlewares: n.middlewares,
+	}
+}
+
+// routeMethods returns the methods of the method handlers and of the `MethodHandler`, if any, sorted.
+func (n *NodeOf[T]) routeMethods() []string {
+	methods := n.Methods()
+	if n.methodHandler == nil {
+		return methods
+	}
+
+	for _, method := range n.methodHandler.methods() {
+		if _, exists := n.methodHandlers[method]; !exists {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// routeParams returns the parameters of the "pattern", in order of appearance.
+// The parameters of the optional parts of the pattern are marked as `Optional`.
+func routeParams(pattern string) (params []RouteParam) {
+	patterns, err := expandOptional(pattern)
+	if err != nil || len(patterns) == 1 {
+		return segmentParams(pattern)
+	}
+
+	// the first expansion has none of the optional parts and the last one has all of them.
+	required := make(map[string]struct{})
+	for _, p := range segmentParams(patterns[0]) {
+		required[p.Name] = struct{}{}
+	}
+
+	params = segmentParams(patterns[len(patterns)-1])
+	for i := range params {
+		if _, ok := required[params[i].Name]; !ok {
+			params[i].Optional = true
+		}
+	}
+
+	return
+}
+
+// expand returns a route for each expansion of the optional parts of its pattern,
+// i.e "/reports" and "/reports/:year" for the "/reports/:year?", or the route itself if it has none.
+func (r Route) expand() []Route {
+	patterns, err := expandOptional(r.Pattern)
+	if err != nil || len(patterns) == 1 {
+		return []Route{r}
+	}
+
+	routes := make([]Route, 0, len(patterns))
+	for _, pattern := range patterns {
+		route := r
+		route.Pattern = pattern
+		route.Params = segmentParams(pattern)
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// segmentParams returns the parameters of the path segments of the "pattern", it has no optional parts.
+func segmentParams(pattern string) (params []RouteParam) {
+	for _, s := range slowPathSplit(pattern) {
+		switch c := s[0]; {
+		case isMixedParam(s):
+			m, err := parseMixedSegment(s, false)
+			if err != nil {
+				continue
+			}
+
+			for _, part := range m.parts {
+				if part.name != "" {
+					params = append(params, RouteParam{Name: part.name, Kind: MixedParam, Constraint: part.constraintKey, Affix: s})
+				}
+			}
+		case c == ParamStart[0]:
+			name, constraint := splitParamConstraint(s[1:])
+			params = append(params, RouteParam{Name: name, Kind: NamedParam, Constraint: constraint})
+		case c == WildcardParamStart[0]:
+			params = append(params, RouteParam{Name: s[1:], Kind: WildcardParam})
+		case isPrefixParam(s):
+			indx := strings.Index(s, PrefixParamStart)
+			params = append(params, RouteParam{Name: s[indx+len(PrefixParamStart):], Kind: PrefixParam, Affix: s[:indx]})
+		case isSuffixParam(s):
+			indx := strings.Index(s, SuffixParamStart)
+			params = append(params, RouteParam{Name: s[:indx], Kind: SuffixParam, Affix: s[indx+len(SuffixParamStart):]})
+		}
+	}
+
+	return
+}
+
+// Walk calls the "fn" for each complete node of the trie, each one is a registered path pattern,
+// parents before their children and children in order of their path segment keys.
+// If "fn" returns an error then the walk stops and Walk returns that error.
+//
+// Walk visits a snapshot of the trie, nodes that are inserted or deleted during the walk are not affected.
+func (t *TrieOf[T]) Walk(fn func(*NodeOf[T]) error) error {
+	return walkNode(t.load().root, fn)
+}
+
+func walkNode[T any](n *NodeOf[T], fn func(*NodeOf[T]) error) error {
+	if n.end {
+		if err := fn(n); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range n.childKeys() {
+		if err := walkNode(n.getChild(s), fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// childKeys returns the keys of the children, sorted.
+func (n *NodeOf[T]) childKeys() []string {
+	if n.children.len() == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, n.children.len())
+	n.children.each(func(s string, _ *NodeOf[T]) {
+		keys = append(keys, s)
+	})
+	sort.Strings(keys)
+
+	return keys
+}
+
+// ListRoutes returns the description of the routes that are registered to this Mux,
+// for a sub mux it returns only the routes under its path prefix.
+// The routes are in the order of `Trie#Walk`, a pattern with optional parts is listed once, as it was registered.
+// The routes of the host patterns follow the ones without a host, sorted by their port and host pattern.
+//
+// It is not named "Routes" because of the `Routes` field.
+func (m *Mux) ListRoutes() (routes []Route) {
+	routes = m.listRoutes(m.Routes, "", routes)
+	m.hosts.each(func(e *hostEntry) {
+		routes = m.listRoutes(e.routes, e.pattern, routes)
+	})
+
+	return
+}
+
+// listRoutes appends the routes of the "table" under this Mux' path prefix to the "routes",
+// their `Route#Host` is the "host".
+func (m *Mux) listRoutes(table *Trie, host string, routes []Route) []Route {
+	// the optional patterns that are already listed, they have a node per expansion.
+	var optional map[string]struct{}
+	table.Walk(func(n *Node) error {
+		if m.root != "" && n.key != m.root && !strings.HasPrefix(n.key, m.root+pathSep) && !strings.HasPrefix(n.key, m.root+OptionalStart) {
+			return nil
+		}
+
+		if n.optional {
+			if _, listed := optional[n.key]; listed {
+				return nil
+			}
+			if optional == nil {
+				optional = make(map[string]struct{})
+			}
+			optional[n.key] = struct{}{}
+		}
+
+		route := n.Route()
+		route.Host = host
+		routes = append(routes, route)
+		return nil
+	})
+
+	return routes
+}
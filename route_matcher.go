@@ -0,0 +1,93 @@
+package muxie
+
+import "net/http"
+
+// matchedHandler is a handler of a route which serves only the requests that pass its matcher, see `WithMatcher`.
+type matchedHandler struct {
+	matcher Matcher
+	handler http.Handler
+}
+
+// NotAcceptableHandler is a handler which just sends the 406 status code and its text.
+// It answers the requests that pass none of the conditions of a route which no other route serves, see `Mux#HandleWhen`.
+// Register it as the handler of a route with conditional handlers to answer these requests
+// without trying the other routes, i.e:
+// mux.HandleWhen("/items/:id", muxie.Accept("application/vnd.x.v2+json"), itemsV2Handler)
+// mux.Handle("/items/:id", muxie.NotAcceptableHandler)
+var NotAcceptableHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+})
+
+// WithMatcher adds a conditional handler to the node, it serves the requests that pass the "matcher".
+// The conditional handlers of a node are tried in order of registration, after the path is matched,
+// before its `Handler` and its handlers per HTTP method, which serve the requests that none of them pass.
+//
+// See `Node#HandlerForRequest` and `Mux#HandleWhen`.
+func WithMatcher(matcher Matcher, handler http.Handler) InsertOption {
+	if matcher == nil {
+		panic("muxie/WithMatcher: empty matcher")
+	}
+
+	if handler == nil {
+		panic("muxie/WithMatcher: empty handler")
+	}
+
+	return func(n *Node) {
+		n.matchedHandlers = append(n.matchedHandlers[:len(n.matchedHandlers):len(n.matchedHandlers)], matchedHandler{matcher: matcher, handler: handler})
+	}
+}
+
+// HandlerForRequest returns the handler of the first conditional handler that the "r" passes, see `WithMatcher`,
+// otherwise the handler for the request's HTTP method, see `HandlerFor`.
+func (n *NodeOf[T]) HandlerForRequest(r *http.Request) http.Handler {
+	for _, h := range n.matchedHandlers {
+		if h.matcher.Match(r) {
+			return h.handler
+		}
+	}
+
+	return n.HandlerFor(r.Method)
+}
+
+// routeConditions returns the descriptions of the matchers of the conditional handlers, in order.
+func (n *NodeOf[T]) routeConditions() []string {
+	if len(n.matchedHandlers) == 0 {
+		return nil
+	}
+
+	conditions := make([]string, 0, len(n.matchedHandlers))
+	for _, h := range n.matchedHandlers {
+		conditions = append(conditions, matcherString(h.matcher))
+	}
+
+	return conditions
+}
+
+// HandleWhen registers a conditional route handler for a path pattern, it serves the requests
+// whose path matches the "pattern" and pass the "matcher", i.e API versioning by the "Accept" header:
+// mux.HandleWhen("/items/:id", muxie.Accept("application/vnd.x.v2+json"), itemsV2Handler)
+// mux.HandleWhen("/items/:id", muxie.Accept("application/vnd.x.v1+json"), itemsV1Handler)
+//
+// The conditional handlers of a pattern are tried in order of registration, the requests that none of them pass
+// are served by the handlers of the pattern registered through `Handle` and `HandleMethod`, if any,
+// otherwise by the next route that the path matches, i.e a "/items/*path", or they are answered
+// with the `NotAcceptableHandler` through the middlewares of the Mux.
+//
+// Unlike the `HandleRequest`, the matcher is checked after the path is matched,
+// the conditions of a route are listed by the `ListRoutes`.
+func (m *Mux) HandleWhen(pattern string, matcher Matcher, handler http.Handler, options ...InsertOption) {
+	beginHandlers := m.getBeginHandlers()
+	wrappers := Pre(beginHandlers...)
+	m.withRoutes("HandleWhen", pattern, true, func(routes *Trie, path string) {
+		routes.Insert(path,
+			append([]InsertOption{WithMatcher(matcher, wrappers.For(handler)), withNotAcceptable(wrappers.For(NotAcceptableHandler)),
+				withMiddlewares(len(beginHandlers))}, options...)...)
+	})
+}
+
+// HandleWhenFunc registers a conditional route handler function for a path pattern.
+//
+// See `HandleWhen`.
+func (m *Mux) HandleWhenFunc(pattern string, matcher Matcher, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleWhen(pattern, matcher, http.HandlerFunc(handlerFunc), options...)
+}
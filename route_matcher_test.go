@@ -0,0 +1,112 @@
+package muxie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMuxHandleWhen(t *testing.T) {
+	const (
+		v1 = "application/vnd.x.v1+json"
+		v2 = "application/vnd.x.v2+json"
+	)
+
+	mux := NewMux()
+	mux.HandleWhenFunc("/items/:id", Accept(v2), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v2 " + GetParam(w, "id")))
+	}, WithTag("item"))
+	mux.HandleWhenFunc("/items/:id", Accept(v1), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v1 " + GetParam(w, "id")))
+	})
+	mux.HandleWhenFunc("/users/:id", Header("X-Admin", ""), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("admin"))
+	})
+	mux.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user"))
+	})
+	mux.HandleWhenFunc("/files/:name", Header("X-Raw", ""), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("raw " + GetParam(w, "name")))
+	})
+	mux.HandleFunc("/files/*path", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("file " + GetParam(w, "path")))
+	})
+
+	tests := []struct {
+		path       string
+		headers    map[string]string
+		statusCode int
+		body       string
+	}{
+		// no "Accept" header accepts any type, the first registered handler serves.
+		{"/items/42", nil, http.StatusOK, "v2 42"},
+		{"/items/42", map[string]string{"Accept": v1}, http.StatusOK, "v1 42"},
+		{"/items/42", map[string]string{"Accept": v2 + ", " + v1 + ";q=0.5"}, http.StatusOK, "v2 42"},
+		// no other route serves it.
+		{"/items/42", map[string]string{"Accept": "text/html"}, http.StatusNotAcceptable, "Not Acceptable\n"},
+		{"/users/1", map[string]string{"X-Admin": "1"}, http.StatusOK, "admin"},
+		{"/users/1", nil, http.StatusOK, "user"},
+		{"/files/a.txt", map[string]string{"X-Raw": "1"}, http.StatusOK, "raw a.txt"},
+		// the next route that the path matches serves it.
+		{"/files/a.txt", nil, http.StatusOK, "file a.txt"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.statusCode || rec.Body.String() != tt.body {
+			t.Fatalf("%s %v: expected: %d %q but got: %d %q", tt.path, tt.headers, tt.statusCode, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+
+	// the handler of the route serves instead of the next route.
+	mux.Handle("/files/:name", NotAcceptableHandler)
+	req := httptest.NewRequest(http.MethodGet, "/files/a.txt", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected status code: %d but got: %d", http.StatusNotAcceptable, rec.Code)
+	}
+
+	if warnings := mux.Routes.Validate(); len(warnings) != 0 {
+		t.Fatalf("expected conditional handlers to not be reported as duplicates but got: %v", warnings)
+	}
+
+	var conditions [][]string
+	for _, route := range mux.ListRoutes() {
+		conditions = append(conditions, route.Conditions)
+	}
+	expected := [][]string{
+		nil,
+		{`Header("X-Raw", "")`},
+		{`Accept("` + v2 + `")`, `Accept("` + v1 + `")`},
+		{`Header("X-Admin", "")`},
+	}
+	if !reflect.DeepEqual(expected, conditions) {
+		t.Fatalf("expected conditions: %v but got: %v", expected, conditions)
+	}
+}
+
+func TestMatcherString(t *testing.T) {
+	tests := []struct {
+		matcher  Matcher
+		expected string
+	}{
+		{And(Method("get, post"), Not(Query("debug", "1"))), `And(Method("GET", "POST"), Not(Query("debug", "1")))`},
+		{Or(Scheme("https"), RemoteCIDR("10.0.0.0/8")), `Or(Scheme("https"), RemoteCIDR("10.0.0.0/8"))`},
+		{Host(".app.com"), `Host(".app.com")`},
+		{MatcherFunc(func(*http.Request) bool { return true }), "muxie.MatcherFunc"},
+	}
+
+	for _, tt := range tests {
+		if got := matcherString(tt.matcher); got != tt.expected {
+			t.Fatalf("expected: %s but got: %s", tt.expected, got)
+		}
+	}
+}
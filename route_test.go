@@ -0,0 +1,84 @@
+package muxie
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestTrieWalk(t *testing.T) {
+	tree := NewTrie()
+	for _, pattern := range []string{"/users/:id", "/", "/users", "/about", "/files/*path"} {
+		tree.Insert(pattern, WithHandler(http.NotFoundHandler()))
+	}
+
+	var patterns []string
+	err := tree.Walk(func(n *Node) error {
+		patterns = append(patterns, n.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"/", "/about", "/files/*path", "/users", "/users/:id"}; !reflect.DeepEqual(expected, patterns) {
+		t.Fatalf("expected patterns: %v but got: %v", expected, patterns)
+	}
+
+	errStop := errors.New("stop")
+	visited := 0
+	err = tree.Walk(func(n *Node) error {
+		if visited++; visited == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || visited != 2 {
+		t.Fatalf("expected the walk to stop at the second node with its error but got: %v after %d nodes", err, visited)
+	}
+}
+
+func TestMuxListRoutes(t *testing.T) {
+	mux := NewMux()
+	mux.Use(func(next http.Handler) http.Handler { return next })
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {}, WithTag("index"))
+
+	v1 := mux.Of("/v1")
+	v1.Use(func(next http.Handler) http.Handler { return next })
+	v1.Get("/users/:id<int>", func(w http.ResponseWriter, r *http.Request) {}, WithData("user"))
+	v1.Delete("/users/:id<int>", func(w http.ResponseWriter, r *http.Request) {})
+	v1.HandleFunc("/files/*path", func(w http.ResponseWriter, r *http.Request) {})
+	v1.HandleFunc("/images/img+:name", func(w http.ResponseWriter, r *http.Request) {})
+	v1.HandleFunc("/docs/name-:.txt", func(w http.ResponseWriter, r *http.Request) {})
+
+	expected := []Route{
+		{Pattern: "/v1/docs/name-:.txt", Params: []RouteParam{{Name: "name", Kind: SuffixParam, Affix: ".txt"}}, Middlewares: 2},
+		{Pattern: "/v1/files/*path", Params: []RouteParam{{Name: "path", Kind: WildcardParam}}, Middlewares: 2},
+		{Pattern: "/v1/images/img+:name", Params: []RouteParam{{Name: "name", Kind: PrefixParam, Affix: "img"}}, Middlewares: 2},
+		{
+			Pattern:     "/v1/users/:id<int>",
+			Params:      []RouteParam{{Name: "id", Kind: NamedParam, Constraint: "int"}},
+			Data:        "user",
+			Methods:     []string{http.MethodDelete, http.MethodGet},
+			Middlewares: 2,
+		},
+	}
+
+	if got := v1.ListRoutes(); !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected routes:\n%#v\nbut got:\n%#v", expected, got)
+	}
+
+	routes := mux.ListRoutes()
+	if len(routes) != len(expected)+1 {
+		t.Fatalf("expected %d routes but got %d", len(expected)+1, len(routes))
+	}
+
+	if got := routes[0]; got.Pattern != "/" || got.Tag != "index" || got.Middlewares != 1 || len(got.Params) != 0 {
+		t.Fatalf("unexpected index route: %#v", got)
+	}
+
+	if kind := SuffixParam.String(); kind != "suffix" {
+		t.Fatalf("expected suffix but got: %s", kind)
+	}
+}
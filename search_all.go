@@ -0,0 +1,78 @@
+package muxie
+
+import "strings"
+
+// MatchOf is a route that a path matches, see `Trie#SearchAll`.
+type MatchOf[T any] struct {
+	Node *NodeOf[T]
+	// Params are the path parameters of the route for the path, in order of appearance.
+	Params []ParamEntry
+	// Specificity is the score of the route for the path, the more specific the route the higher the score.
+	// Each of the first `maxSpecificitySegments` path segments takes 3 bits, the first segment the most significant ones,
+	// with the rank of the rule that matched it: 6 for a static segment, 5 for a prefix parameter, 4 for a suffix one,
+	// 3 for mixed parameters, 2 for a constrained parameter, 1 for a named one and 0 for a wildcard.
+	// So a route with a static segment where another one has a parameter scores higher, like the `Search` precedence.
+	Specificity uint64
+}
+
+// Match is a route of the `Trie` that a path matches.
+type Match = MatchOf[interface{}]
+
+// maxSpecificitySegments is the number of the path segments that the `Match.Specificity` takes into account.
+const maxSpecificitySegments = 21
+
+// segmentSpecificity returns the score of the path segment at "depth" which is matched by the "rule", see `Match`.
+func segmentSpecificity(rule MatchRule, depth int) uint64 {
+	if depth >= maxSpecificitySegments {
+		return 0
+	}
+
+	var rank uint64
+	switch rule {
+	case MatchStatic:
+		rank = 6
+	case MatchPrefixParam:
+		rank = 5
+	case MatchSuffixParam:
+		rank = 4
+	case MatchMixedParam:
+		rank = 3
+	case MatchConstrainedParam:
+		rank = 2
+	case MatchNamedParam:
+		rank = 1
+	}
+
+	return rank << (3 * uint(maxSpecificitySegments-1-depth))
+}
+
+// SearchAll returns every route that the "path" matches, the most specific first.
+// They are ordered by the precedence of the backtracking search, see `TrieOptions.Backtracking`,
+// so the first one is the route that a backtracking `Search` returns, the wildcards of the parents follow,
+// i.e "/api/users/list", "/api/users/:id", "/api/*path" and "/*path" for the "/api/users/list".
+// It returns nil if the path matches no route.
+//
+// Useful to apply layered policies, i.e an org-wide wildcard, a service prefix and an exact endpoint.
+func (t *TrieOf[T]) SearchAll(path string) []MatchOf[T] {
+	tree := t.load()
+
+	if path == "" || path == pathSep {
+		var matches []MatchOf[T]
+		if tree.hasRootSlash {
+			matches = append(matches, MatchOf[T]{Node: tree.root.getChild(pathSep), Specificity: segmentSpecificity(MatchStatic, 0)})
+		}
+		if tree.hasRootWildcard {
+			matches = append(matches, MatchOf[T]{Node: tree.root.getChild(WildcardParamStart)})
+		}
+
+		return matches
+	}
+
+	b := backtracker[T]{q: path, qc: path, collect: true}
+	if t.caseInsensitive {
+		b.qc = strings.ToLower(path)
+	}
+	b.match(tree.root, 1)
+
+	return b.matches
+}
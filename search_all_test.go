@@ -0,0 +1,79 @@
+package muxie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrieSearchAll(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/*any", WithTag("org"))
+	tree.Insert("/api/*path", WithTag("service"))
+	tree.Insert("/api/users/:id", WithTag("user"))
+	tree.Insert("/api/users/:id<int>", WithTag("user_int"))
+	tree.Insert("/api/users/list", WithTag("list"))
+	tree.Insert("/api/:resource/list", WithTag("resource_list"))
+	tree.Insert("/", WithTag("index"))
+
+	tests := []struct {
+		path   string
+		tags   []string
+		params [][]ParamEntry
+	}{
+		{"/api/users/list", []string{"list", "user", "resource_list", "service", "org"}, [][]ParamEntry{
+			nil,
+			{{"id", "list"}},
+			{{"resource", "users"}},
+			{{"path", "users/list"}},
+			{{"any", "api/users/list"}},
+		}},
+		{"/api/users/42", []string{"user_int", "user", "service", "org"}, [][]ParamEntry{
+			{{"id", "42"}},
+			{{"id", "42"}},
+			{{"path", "users/42"}},
+			{{"any", "api/users/42"}},
+		}},
+		{"/other", []string{"org"}, [][]ParamEntry{{{"any", "other"}}}},
+		{"/", []string{"index", "org"}, [][]ParamEntry{nil, nil}},
+	}
+
+	for _, tt := range tests {
+		matches := tree.SearchAll(tt.path)
+		if len(matches) != len(tt.tags) {
+			t.Fatalf("%s: expected %d matches but got: %d", tt.path, len(tt.tags), len(matches))
+		}
+
+		for i, m := range matches {
+			if m.Node.Tag != tt.tags[i] {
+				t.Fatalf("%s: [%d] expected: %s but got: %s", tt.path, i, tt.tags[i], m.Node.Tag)
+			}
+
+			if !reflect.DeepEqual(tt.params[i], m.Params) {
+				t.Fatalf("%s: [%d] expected params: %v but got: %v", tt.path, i, tt.params[i], m.Params)
+			}
+
+			if i > 0 && m.Specificity > matches[i-1].Specificity {
+				t.Fatalf("%s: [%d] expected the specificity to not increase but got: %d after %d", tt.path, i, m.Specificity, matches[i-1].Specificity)
+			}
+		}
+	}
+
+	if matches := NewTrie().SearchAll("/api"); matches != nil {
+		t.Fatalf("expected no matches but got: %v", matches)
+	}
+}
+
+func TestTrieSearchAllFirstIsBacktrackingSearch(t *testing.T) {
+	tree := NewTrieWithOptions(TrieOptions{Backtracking: true, Compress: true})
+	for _, pattern := range []string{"/a/b/c/z", "/a/:p1/c/d", "/a/b/*rest", "/a/:p1/:p2/d", "/files/:name.:ext", "/files/img+:name"} {
+		tree.Insert(pattern, WithTag(pattern))
+	}
+
+	for _, path := range []string{"/a/b/c/d", "/a/b/c/z", "/a/x/c/d", "/a/x/y/d", "/files/img.png", "/files/a.b"} {
+		matches := tree.SearchAll(path)
+		n := tree.Search(path, new(Writer))
+		if len(matches) == 0 || matches[0].Node != n {
+			t.Fatalf("%s: expected the first match to be the node of the search: %v but got: %v", path, n, matches)
+		}
+	}
+}
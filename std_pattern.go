@@ -0,0 +1,211 @@
+package muxie
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// ParseStdPattern converts a `net/http#ServeMux` pattern of Go 1.22+, i.e "GET /items/{id}",
+// to its HTTP method, if any, and the equivalent path pattern of the muxie's `Trie`:
+//
+//	"{name}" is converted to a named parameter ":name"
+//	"{name...}" is converted to a wildcard "*name"
+//	"/path/" matches the whole "/path/" subtree, it is converted to the "/path/*" wildcard without a name
+//	"/path/{$}" is converted to the "/path/*" wildcard as well, the `HandleStd` matches it only for the "/path/"
+//	"/{$}" is converted to "/", an exact match
+//	"/" matches all paths, it is converted to the "/*" root wildcard
+//
+// The names of the wildcards should be Go identifiers and unique in the pattern, like the ServeMux requires.
+//
+// The `HandleStd` routes match the paths that the ServeMux does, with these differences:
+// the "/path/", "/path/{$}" and "/path/{name...}" are the same route for the Trie, the last registration wins,
+// the routes are chosen by the precedence of the Trie instead of the most specific pattern,
+// so the patterns that the ServeMux rejects as conflicting are accepted, and host patterns are not supported.
+func ParseStdPattern(pattern string) (method, path string, err error) {
+	method, path, _, err = parseStdPattern(pattern)
+	return
+}
+
+// parseStdPattern is the `ParseStdPattern`, it reports whether the pattern ends with a "{$}" after a slash,
+// so its wildcard should match only an empty path, as well.
+func parseStdPattern(pattern string) (method, path string, exact bool, err error) {
+	rest := strings.TrimLeft(pattern, " \t")
+	if i := strings.IndexAny(rest, " \t"); i != -1 {
+		method, rest = rest[:i], strings.TrimLeft(rest[i:], " \t")
+		if method == "" || strings.ContainsAny(method, "/{}") {
+			return "", "", false, fmt.Errorf("muxie: pattern %q: invalid method %q", pattern, method)
+		}
+	}
+
+	i := strings.IndexByte(rest, pathSepB)
+	if i == -1 {
+		return "", "", false, fmt.Errorf("muxie: pattern %q: missing the path", pattern)
+	}
+	if i > 0 {
+		return "", "", false, fmt.Errorf("muxie: pattern %q: host %q is not supported", pattern, rest[:i])
+	}
+
+	segments := strings.Split(rest[1:], pathSep)
+	names := make(map[string]struct{})
+	var b strings.Builder
+	for idx, segment := range segments {
+		last := idx == len(segments)-1
+
+		switch {
+		case segment == "":
+			if !last {
+				return "", "", false, fmt.Errorf("muxie: pattern %q: empty path segment", pattern)
+			}
+			// trailing slash, a subtree.
+			b.WriteString(pathSep + WildcardParamStart)
+		case segment == "{$}":
+			if !last {
+				return "", "", false, fmt.Errorf("muxie: pattern %q: {$} is not the last path segment", pattern)
+			}
+			if b.Len() == 0 {
+				b.WriteString(pathSep)
+			} else {
+				// the subtree without the rest of the paths, see `Mux#HandleStd`.
+				b.WriteString(pathSep + WildcardParamStart)
+				exact = true
+			}
+		case segment[0] == '{':
+			if segment[len(segment)-1] != '}' {
+				return "", "", false, fmt.Errorf("muxie: pattern %q: bad wildcard segment %q", pattern, segment)
+			}
+
+			name := segment[1 : len(segment)-1]
+			multi := strings.HasSuffix(name, "...")
+			name = strings.TrimSuffix(name, "...")
+			if !isIdentifier(name) {
+				return "", "", false, fmt.Errorf("muxie: pattern %q: bad wildcard name %q", pattern, name)
+			}
+			if _, exists := names[name]; exists {
+				return "", "", false, fmt.Errorf("muxie: pattern %q: duplicate wildcard name %q", pattern, name)
+			}
+			names[name] = struct{}{}
+
+			if multi {
+				if !last {
+					return "", "", false, fmt.Errorf("muxie: pattern %q: %s is not the last path segment", pattern, segment)
+				}
+				b.WriteString(pathSep + WildcardParamStart + name)
+			} else {
+				b.WriteString(pathSep + ParamStart + name)
+			}
+		case strings.ContainsAny(segment, "{}"):
+			return "", "", false, fmt.Errorf("muxie: pattern %q: bad wildcard segment %q, wildcards should be whole segments", pattern, segment)
+		default:
+			b.WriteString(pathSep + segment)
+		}
+	}
+
+	return method, b.String(), exact, nil
+}
+
+// isIdentifier reports whether the "name" is a Go identifier, like the names of the ServeMux wildcards.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HandleStd registers a route handler for a `net/http#ServeMux` pattern of Go 1.22+,
+// i.e "GET /items/{id}", "/files/{path...}" or "/{$}",
+// so route tables can be shared between the two.
+// The path parameters are available through the `GetParam` and, on Go 1.22+, through the `Request.PathValue`.
+// Panics on invalid patterns, as the `ServeMux` does.
+//
+// Like the ServeMux, the path without the trailing slash of a pattern which ends with a slash, a "{$}" or a "{name...}"
+// is redirected to the path with the slash, i.e "/static" to the "/static/", unless another route matches it exactly.
+// The redirects are disabled when the `PathCorrection` is true, as it removes the trailing slashes.
+//
+// See `ParseStdPattern` for more.
+func (m *Mux) HandleStd(pattern string, handler http.Handler, options ...InsertOption) {
+	method, path, exact, err := parseStdPattern(pattern)
+	if err != nil {
+		panic("muxie/Mux#HandleStd: " + err.Error())
+	}
+
+	redirect := isWildcardEnd(path) && path != pathSep+WildcardParamStart
+	if redirect {
+		atomic.StoreUint32(m.slashRedirects, 1)
+	}
+
+	options = append(options, withStdPattern(exact, redirect))
+	if method == "" {
+		m.Handle(path, handler, options...)
+	} else {
+		m.HandleMethod(method, path, handler, options...)
+	}
+}
+
+// HandleStdFunc registers a route handler function for a `net/http#ServeMux` pattern of Go 1.22+.
+//
+// See `HandleStd`.
+func (m *Mux) HandleStdFunc(pattern string, handlerFunc func(http.ResponseWriter, *http.Request), options ...InsertOption) {
+	m.HandleStd(pattern, http.HandlerFunc(handlerFunc), options...)
+}
+
+// withStdPattern marks the node of a `HandleStd` pattern, see `Node.exactSlash` and `Node.redirectSlash`.
+func withStdPattern(exactSlash, redirectSlash bool) InsertOption {
+	return func(n *Node) {
+		n.pathValues = true
+		n.exactSlash = exactSlash
+		n.redirectSlash = redirectSlash
+	}
+}
+
+// isWildcardEnd reports whether the last path segment of the "pattern" is a wildcard.
+func isWildcardEnd(pattern string) bool {
+	i := strings.LastIndexByte(pattern, pathSepB) + 1
+	return i < len(pattern) && pattern[i] == WildcardParamStart[0]
+}
+
+// emptyWildcard reports whether the value of the last parameter, the wildcard of its node, is empty,
+// i.e the "/path/" of the "/path/*".
+func emptyWildcard(params []ParamEntry) bool {
+	return len(params) > 0 && params[len(params)-1].Value == ""
+}
+
+// stdMatch reports whether the path parameters "params" of this `HandleStd` route match it like the ServeMux does,
+// its named parameters are not empty and the wildcard of a "/path/{$}" is empty.
+func (n *NodeOf[T]) stdMatch(params []ParamEntry) bool {
+	last := len(params) - 1
+	for i, p := range params {
+		if p.Value == "" && (i < last || !isWildcardEnd(n.key)) {
+			return false
+		}
+	}
+
+	return !n.exactSlash || emptyWildcard(params)
+}
+
+// redirectSlash reports whether the request of the "path" should be redirected to the path with a trailing slash,
+// the path with the slash matches the empty wildcard of a `HandleStd` route, i.e the "/static" of the "/static/".
+// The "n" is the route that the path matches, if any, the path is not redirected if it matches a route other than a wildcard.
+func (m *Mux) redirectSlash(r *http.Request, path string, n *Node) bool {
+	if atomic.LoadUint32(m.slashRedirects) == 0 || m.PathCorrection || path == "" || path[len(path)-1] == pathSepB ||
+		n != nil && !isWildcardEnd(n.key) {
+		return false
+	}
+
+	pw := m.paramsPool.Get().(*Writer)
+	pw.reset(nil)
+	next, _, _ := m.search(r, path+pathSep, pw)
+	ok := next != nil && next.redirectSlash && emptyWildcard(pw.params)
+	m.paramsPool.Put(pw)
+
+	return ok
+}
@@ -0,0 +1,78 @@
+//go:build go1.22
+// +build go1.22
+
+// the module's Go version selects the ServeMux of Go 1.21, which does not know the patterns of Go 1.22.
+//go:debug httpmuxgo121=0
+
+package muxie
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestMuxHandleStdServeMux compares the routes of the HandleStd with the ones of the net/http#ServeMux.
+func TestMuxHandleStdServeMux(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		requests []string
+	}{
+		{[]string{"/path/{$}"}, []string{"GET /path", "GET /path/", "GET /path/x", "GET /path?q=1"}},
+		{[]string{"/static/"}, []string{"GET /static", "GET /static/", "GET /static/css/main.css"}},
+		{[]string{"/path", "/path/{$}"}, []string{"GET /path", "GET /path/", "GET /path/x"}},
+		{[]string{"/static/", "/static"}, []string{"GET /static", "GET /static/", "GET /static/x"}},
+		{[]string{"/", "/{$}"}, []string{"GET /", "GET /x", "GET /x/"}},
+		{[]string{"/", "/path/{$}"}, []string{"GET /path", "GET /path/", "GET /path/x"}},
+		{[]string{"/", "/a/{$}", "/a/b/{$}"}, []string{"GET /a/b/c", "GET /a/b/", "GET /a/b", "GET /a/x"}},
+		{[]string{"/a/{x}"}, []string{"GET /a", "GET /a/", "GET /a/1", "GET /a/1/", "GET /a/1/2"}},
+		{[]string{"/a/{x}/"}, []string{"GET /a/1", "GET /a/1/", "GET /a/1/2"}},
+		{[]string{"/a/{x...}"}, []string{"GET /a", "GET /a/", "GET /a/1", "GET /a/1/2/"}},
+		{[]string{"GET /items/{id}", "DELETE /items/{id}"}, []string{"GET /items/42", "DELETE /items/42", "HEAD /items/42", "POST /items/42"}},
+		{[]string{"/users/{id}/files/{path...}"}, []string{"GET /users/42/files/a/b.txt", "GET /users/42/files"}},
+	}
+
+	for _, tt := range tests {
+		std := http.NewServeMux()
+		mux := NewMux()
+		for _, pattern := range tt.patterns {
+			pattern := pattern
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "%s x=%s id=%s path=%s", pattern, r.PathValue("x"), r.PathValue("id"), r.PathValue("path"))
+			}
+			std.HandleFunc(pattern, handler)
+			mux.HandleStdFunc(pattern, handler)
+		}
+
+		for _, request := range tt.requests {
+			method, target, _ := strings.Cut(request, " ")
+
+			expected, got := httptest.NewRecorder(), httptest.NewRecorder()
+			std.ServeHTTP(expected, httptest.NewRequest(method, target, nil))
+			mux.ServeHTTP(got, httptest.NewRequest(method, target, nil))
+
+			// the ServeMux of older Go versions redirects with a 301.
+			if isRedirect(expected.Code) {
+				if !isRedirect(got.Code) || expected.Header().Get("Location") != got.Header().Get("Location") {
+					t.Fatalf("%v: %s: expected a redirect to: %q but got: %d %q", tt.patterns, request,
+						expected.Header().Get("Location"), got.Code, got.Header().Get("Location"))
+				}
+				continue
+			}
+
+			if expected.Code != got.Code {
+				t.Fatalf("%v: %s: expected status code: %d but got: %d", tt.patterns, request, expected.Code, got.Code)
+			}
+
+			if expected.Code == http.StatusOK && expected.Body.String() != got.Body.String() {
+				t.Fatalf("%v: %s: expected body: %q but got: %q", tt.patterns, request, expected.Body.String(), got.Body.String())
+			}
+		}
+	}
+}
+
+func isRedirect(code int) bool {
+	return code == http.StatusMovedPermanently || code == http.StatusTemporaryRedirect
+}
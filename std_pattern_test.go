@@ -0,0 +1,94 @@
+package muxie
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestParseStdPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		method  string
+		path    string
+		err     bool
+	}{
+		{"/", "", "/*", false},
+		{"/{$}", "", "/", false},
+		{"GET /items/{id}", http.MethodGet, "/items/:id", false},
+		{"DELETE  /items/{id}/tags/{tag}", http.MethodDelete, "/items/:id/tags/:tag", false},
+		{"/files/{path...}", "", "/files/*path", false},
+		{"/static/", "", "/static/*", false},
+		{"/static/{$}", "", "/static/*", false},
+		{"/files/{_path2...}", "", "/files/*_path2", false},
+		{"POST /static", http.MethodPost, "/static", false},
+		{"example.com/", "", "", true},
+		{"GET", "", "", true},
+		{"/files/{path...}/more", "", "", true},
+		{"/files/{$}/more", "", "", true},
+		{"/files/a{id}", "", "", true},
+		{"/files/{id", "", "", true},
+		{"/files//more", "", "", true},
+		{"/files/{}", "", "", true},
+		{"/files/{...}", "", "", true},
+		{"/files/{a b}", "", "", true},
+		{"/files/{1a}", "", "", true},
+		{"/files/{a-b}", "", "", true},
+		{"/files/{id}/{id}", "", "", true},
+	}
+
+	for i, tt := range tests {
+		method, path, err := ParseStdPattern(tt.pattern)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("[%d] %s: expected an error", i, tt.pattern)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("[%d] %s: %v", i, tt.pattern, err)
+		}
+
+		if method != tt.method {
+			t.Fatalf("[%d] %s: expected method: '%s' but got: '%s'", i, tt.pattern, tt.method, method)
+		}
+
+		if path != tt.path {
+			t.Fatalf("[%d] %s: expected path: '%s' but got: '%s'", i, tt.pattern, tt.path, path)
+		}
+	}
+}
+
+func TestMuxHandleStd(t *testing.T) {
+	mux := NewMux()
+	mux.HandleStdFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "catch all: %s", r.URL.Path)
+	})
+	mux.HandleStdFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "index")
+	})
+	mux.HandleStdFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "item: %s", GetParam(w, "id"))
+	})
+	mux.HandleStdFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "static: %s", r.URL.Path)
+	})
+
+	testHandler(t, mux, http.MethodGet, "/").statusCode(http.StatusOK).bodyEq("index")
+	testHandler(t, mux, http.MethodGet, "/other").statusCode(http.StatusOK).bodyEq("catch all: /other")
+	testHandler(t, mux, http.MethodGet, "/items/42").statusCode(http.StatusOK).bodyEq("item: 42")
+	testHandler(t, mux, http.MethodPost, "/items/42").statusCode(http.StatusMethodNotAllowed)
+	testHandler(t, mux, http.MethodGet, "/static/").statusCode(http.StatusOK).bodyEq("static: /static/")
+	testHandler(t, mux, http.MethodGet, "/static/css/main.css").statusCode(http.StatusOK).bodyEq("static: /static/css/main.css")
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected a panic for an invalid pattern")
+			}
+		}()
+
+		mux.HandleStdFunc("/files/{path...}/more", func(w http.ResponseWriter, r *http.Request) {})
+	}()
+}
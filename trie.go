@@ -3,6 +3,8 @@ package muxie
 import (
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -19,19 +21,32 @@ const (
 	SuffixParamStart = "-:"
 )
 
-// Trie contains the main logic for adding and searching nodes for path segments.
+// TrieOf contains the main logic for adding and searching nodes for path segments.
 // It supports wildcard and named path parameters.
+// The "T" is the type of the nodes' `Data`, see `NodeOf` and `WithDataOf`, the `Trie` stores any data.
+// Only the `Data` is generic, the nodes keep their HTTP fields, i.e `Handler` and `Tag`, for any "T".
 // Trie supports very coblex and useful path patterns for routes.
 // The Trie checks for static paths(path without : or *) and named parameters before that in order to support everything that other implementations do not,
 // and if nothing else found then it tries to find the closest wildcard path(super and unique).
-type Trie struct {
-	root *Node
-
-	// if true then it will handle any path if not other parent wildcard exists,
-	// so even 404 (on http services) is up to it, see Trie#Insert.
-	hasRootWildcard bool
-
-	hasRootSlash bool
+//
+// Trie is safe for concurrent use: the nodes are copied on write,
+// so searches never block and never see a half-built trie while routes are inserted or deleted.
+// See `Update` to apply many changes at once.
+type TrieOf[T any] struct {
+	// mu serializes the writers.
+	mu sync.Mutex
+	// tree holds the current *trieTree, it is swapped by the writers
+	// and loaded by the readers without locking, a tree that a reader has loaded is never modified.
+	tree atomic.Value
+	// if true then the tree is not visible to the readers and the writes modify it in place,
+	// i.e the transaction of an `Update`.
+	private bool
+	// twin is a copy of the current tree that the readers have not seen,
+	// the next write modifies it instead of copying the whole tree, see `write`.
+	twin *trieTree[T]
+	// seen is set to 1 by the first reader, after that the previous trees
+	// are no longer reused as twins.
+	seen uint32
 
 	// If true then named path parameters that weren't explored will be considered if no match is found,
 	// useful in situations where a named parameter value conflicts with segment in a fixed path.
@@ -44,11 +59,72 @@ type Trie struct {
 	searchUnvisitedParams bool
 
 	caseInsensitive bool
+
+	// if true then `Insert` panics on conflicting routes, see `Strict`.
+	strict bool
+
+	// if true then the chains of static path segments are merged to a single node, see `Compress`.
+	compress bool
+
+	// if true then the search explores every candidate, see `Backtracking`.
+	backtracking bool
+
+	// the number of children that a node keeps in a sorted slice before it switches to a map, see `nodeChildren`.
+	maxSortedChildren int
+
+	// the serialization of the nodes' data and handlers, see `DataCodec` and `HandlerResolver`.
+	codec    DataCodecOf[T]
+	resolver HandlerResolver
+}
+
+// Trie is the trie of the Mux, its nodes can store any data.
+// See `TrieOf`.
+type Trie = TrieOf[interface{}]
+
+// trieTree is an immutable, once published to the readers, version of a Trie's nodes.
+type trieTree[T any] struct {
+	root *NodeOf[T]
+
+	// if true then it will handle any path if not other parent wildcard exists,
+	// so even 404 (on http services) is up to it, see Trie#Insert.
+	hasRootWildcard bool
+
+	hasRootSlash bool
+
+	// the complete nodes by their tags, built on the first `Reverse`.
+	tagsOnce sync.Once
+	tags     map[string]*NodeOf[T]
+
+	// a node without children, nothing matches against it.
+	empty *NodeOf[T]
+}
+
+func (tree *trieTree[T]) clone() *trieTree[T] {
+	return &trieTree[T]{
+		root:            tree.root.clone(nil),
+		hasRootWildcard: tree.hasRootWildcard,
+		hasRootSlash:    tree.hasRootSlash,
+		empty:           tree.empty,
+	}
 }
 
 type TrieOptions struct {
 	CaseInsensitive       bool
 	SearchUnvisitedParams bool
+	// Strict makes the `Insert` panic on conflicting routes, see `Trie#Strict`.
+	Strict bool
+	// Compress merges the chains of static path segments to a single node, see `Trie#Compress`.
+	Compress bool
+	// Backtracking makes the `Search` explore every child that a path segment matches and return
+	// the complete node of the highest priority, instead of following the first child that matches.
+	// The routes are compared path segment by path segment, from left to right, the first segment that they match
+	// differently decides by the priority of the `Search`: static segments, then prefix and suffix parameters
+	// with the longest literal text first, mixed parameters, constrained, named parameters and last the wildcards.
+	// For example, with the routes "/a/b/c/z" and "/a/:p1/c/d" the path "/a/b/c/d" is matched by the second one
+	// and the "/a/b/c/z" by the first one, a "/a/b/*rest" would match the "/a/b/c/d" instead because its "b" is static.
+	// The `SearchUnvisitedParams` has no effect, the closest wildcard is the one of the highest priority.
+	// It is slower than the default search only for the paths that need to go back, see `Trie#Backtracking`.
+	Backtracking bool
 }
 
 // NewTrie returns a new, empty Trie.
@@ -56,39 +132,168 @@ type TrieOptions struct {
 //
 // See `Trie`
 func NewTrie() *Trie {
-	return &Trie{
-		root:                  NewNode(),
-		hasRootWildcard:       false,
-		caseInsensitive:       false,
-		searchUnvisitedParams: false,
-	}
+	return NewTrieWithOptions(TrieOptions{})
 }
 
 func NewTrieWithOptions(options TrieOptions) *Trie {
-	return &Trie{
-		root:                  NewNode(),
-		hasRootWildcard:       false,
+	return NewTrieOfWithOptions[interface{}](options)
+}
+
+// NewTrieOf returns a new, empty Trie which stores data of type "T" to its nodes, i.e:
+// rules := muxie.NewTrieOf[*RuleSet]()
+// rules.Insert("/orgs/:org/*path", muxie.WithDataOf(orgRules))
+// n := rules.Search("/orgs/acme/projects", params) // n.Data is a *RuleSet.
+func NewTrieOf[T any]() *TrieOf[T] {
+	return NewTrieOfWithOptions[T](TrieOptions{})
+}
+
+// NewTrieOfWithOptions is like the `NewTrieOf` but with options.
+func NewTrieOfWithOptions[T any](options TrieOptions) *TrieOf[T] {
+	t := &TrieOf[T]{
 		caseInsensitive:       options.CaseInsensitive,
 		searchUnvisitedParams: options.SearchUnvisitedParams,
+		strict:                options.Strict,
+		compress:              options.Compress,
+		backtracking:          options.Backtracking,
+		maxSortedChildren:     defaultMaxSortedChildren,
+	}
+	t.tree.Store(&trieTree[T]{root: new(NodeOf[T]), empty: new(NodeOf[T])})
+	return t
+}
+
+// load returns the current tree, it is safe to read its nodes without locks.
+// It never blocks, the writers never modify a tree that a reader may have loaded.
+func (t *TrieOf[T]) load() *trieTree[T] {
+	if atomic.LoadUint32(&t.seen) == 0 {
+		atomic.StoreUint32(&t.seen, 1)
+	}
+
+	return t.tree.Load().(*trieTree[T])
+}
+
+// write calls "fn" with a tree that the readers can not see
+// and publishes it when "fn" returns.
+//
+// Until the first reader, the previous tree is brought up to date by calling "fn" on it too
+// and it is kept as the twin of the next write, so registering routes one by one does not copy the trie each time.
+func (t *TrieOf[T]) write(fn func(tree *trieTree[T])) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current := t.tree.Load().(*trieTree[T])
+	if t.private {
+		fn(current)
+		return
+	}
+
+	// a panic of "fn" leaves the twin half modified, drop it until "fn" returns.
+	tree := t.twin
+	t.twin = nil
+	if tree == nil {
+		tree = current.clone()
+	}
+
+	fn(tree)
+	t.tree.Store(tree)
+
+	// the readers set "seen" before they load the tree, so if it is not set
+	// no reader holds the previous tree and it can be modified in place.
+	if atomic.LoadUint32(&t.seen) == 0 {
+		fn(current)
+		t.twin = current
+	}
+}
+
+// Update calls "fn" with a transaction Trie, the changes of the "fn"
+// are published to the readers at once, when the "fn" returns.
+// Useful to insert or delete many nodes on a trie that is already being searched,
+// as each `Insert` and `Delete` outside of an `Update` copies the whole trie once it is searched.
+//
+// The "fn" should not call this Trie's methods, only the transaction's ones.
+func (t *TrieOf[T]) Update(fn func(tx *TrieOf[T])) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tree := t.twin
+	t.twin = nil
+	if tree == nil {
+		tree = t.tree.Load().(*trieTree[T]).clone()
+	}
+
+	tx := t.fork(tree)
+	tx.private = true
+	fn(tx)
+	t.tree.Store(tx.tree.Load())
+}
+
+// fork returns a new Trie of the "tree" with the options of this Trie.
+func (t *TrieOf[T]) fork(tree *trieTree[T]) *TrieOf[T] {
+	f := &TrieOf[T]{
+		caseInsensitive:       t.caseInsensitive,
+		searchUnvisitedParams: t.searchUnvisitedParams,
+		strict:                t.strict,
+		compress:              t.compress,
+		backtracking:          t.backtracking,
+		maxSortedChildren:     t.maxSortedChildren,
+		codec:                 t.codec,
+		resolver:              t.resolver,
+	}
+	f.tree.Store(tree)
+
+	return f
+}
+
+// clone returns a copy of this Trie, its changes are not visible to this one.
+func (t *TrieOf[T]) clone() *TrieOf[T] {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.fork(t.tree.Load().(*trieTree[T]).clone())
+}
+
+// options returns the options of this Trie.
+func (t *TrieOf[T]) options() TrieOptions {
+	return TrieOptions{
+		CaseInsensitive:       t.caseInsensitive,
+		SearchUnvisitedParams: t.searchUnvisitedParams,
+		Strict:                t.strict,
+		Compress:              t.compress,
+		Backtracking:          t.backtracking,
 	}
 }
 
 // Sets the option to search invisited named parameter nodes
-func (t *Trie) SearchUnvisitedParams() *Trie {
+func (t *TrieOf[T]) SearchUnvisitedParams() *TrieOf[T] {
 	t.searchUnvisitedParams = true
 	return t
 }
 
 // Sets the trie to match paths without case sensitivity
-func (t *Trie) CaseInsensitive() *Trie {
+func (t *TrieOf[T]) CaseInsensitive() *TrieOf[T] {
 	t.caseInsensitive = true
 	return t
 }
 
-// InsertOption is just a function which accepts a pointer to a Node which can alt its `Handler`, `Tag` and `Data`  fields.
+// Strict makes the `Insert` panic with a descriptive error instead of accepting an ambiguous route:
+// a duplicate route, a parameter named differently than the one of another route for the same path segment,
+// prefix and suffix parameters that shadow each other and a wildcard that is not the last path segment.
+// Without it, these are reported by the `Validate`.
+//
+// See `TryInsert` too.
+func (t *TrieOf[T]) Strict() *TrieOf[T] {
+	t.strict = true
+	return t
+}
+
+// InsertOptionOf is just a function which accepts a pointer to a Node which can alt its `Handler`, `Tag` and `Data`  fields.
+//
+// See `WithTagOf` and `WithDataOf`.
+type InsertOptionOf[T any] func(*NodeOf[T])
+
+// InsertOption is the insert option of the `Trie`.
 //
 // See `WithHandler`, `WithTag` and `WithData`.
-type InsertOption func(*Node)
+type InsertOption = InsertOptionOf[interface{}]
 
 // WithHandler sets the node's `Handler` field (useful for HTTP).
 func WithHandler(handler http.Handler) InsertOption {
@@ -96,40 +301,192 @@ func WithHandler(handler http.Handler) InsertOption {
 		panic("muxie/WithHandler: empty handler")
 	}
 
+	return withHandler(handler, nil)
+}
+
+// withHandler is like `WithHandler` but it keeps the "methodHandler" that the "handler" wraps, if any.
+func withHandler(handler http.Handler, methodHandler *MethodHandler) InsertOption {
 	return func(n *Node) {
-		if n.Handler == nil {
-			n.Handler = handler
-		}
+		// the last registered handler wins.
+		n.Handler = handler
+		n.methodHandler = methodHandler
 	}
 }
 
-// WithTag sets the node's `Tag` field (may be useful for HTTP).
-func WithTag(tag string) InsertOption {
+// WithMethodHandler sets the node's handler for a specific HTTP method.
+// An existing handler for the same method is replaced, the handlers of the other methods are kept.
+//
+// See `Node#HandlerFor` and `Mux#HandleMethod`.
+func WithMethodHandler(method string, handler http.Handler) InsertOption {
+	if handler == nil {
+		panic("muxie/WithMethodHandler: empty handler")
+	}
+
+	method = normalizeMethod(method)
 	return func(n *Node) {
-		if n.Tag == "" {
-			n.Tag = tag
+		if n.methodHandlers == nil {
+			n.methodHandlers = make(map[string]http.Handler)
 		}
+
+		n.methodHandlers[method] = handler
+	}
+}
+
+func withMiddlewares(count int) InsertOption {
+	return func(n *Node) {
+		n.middlewares = count
+	}
+}
+
+func withMethodFallback(handler http.Handler) InsertOption {
+	return func(n *Node) {
+		n.methodFallback = handler
+	}
+}
+
+func withNotAcceptable(handler http.Handler) InsertOption {
+	return func(n *Node) {
+		n.notAcceptable = handler
+	}
+}
+
+// WithTag sets the node's `Tag` field (may be useful for HTTP),
+// the tag is the name of the route for `Trie#Reverse` and `Mux#URL`.
+func WithTag(tag string) InsertOption {
+	return WithTagOf[interface{}](tag)
+}
+
+// WithTagOf is the `WithTag` for a `TrieOf`.
+func WithTagOf[T any](tag string) InsertOptionOf[T] {
+	return func(n *NodeOf[T]) {
+		n.Tag = tag
 	}
 }
 
 // WithData sets the node's optionally `Data` field.
 func WithData(data interface{}) InsertOption {
-	return func(n *Node) {
+	return WithDataOf(data)
+}
+
+// WithDataOf sets the node's `Data` field of a `TrieOf`, the "T" is inferred from the "data".
+func WithDataOf[T any](data T) InsertOptionOf[T] {
+	return func(n *NodeOf[T]) {
 		// data can be replaced.
 		n.Data = data
 	}
 }
 
 // Insert adds a node to the trie.
-func (t *Trie) Insert(pattern string, options ...InsertOption) {
+// Ambiguous routes are reported by `Validate`, or they panic if the trie is `Strict`.
+//
+// The "pattern" may have optional parts, i.e "/reports/:year?" or "/docs[/:lang]",
+// a node is added for each of its expansions, i.e "/reports" and "/reports/:year", with the same "options".
+// The nodes keep the original pattern as their key, the parameters of the absent parts are not set by the `Search`.
+func (t *TrieOf[T]) Insert(pattern string, options ...InsertOptionOf[T]) {
 	if pattern == "" {
 		panic("muxie/trie#Insert: empty pattern")
 	}
 
-	n := t.insert(pattern, "", nil, nil)
-	for _, opt := range options {
-		opt(n)
+	patterns := insertPatterns("Insert", pattern)
+	t.write(func(tree *trieTree[T]) {
+		for _, expanded := range patterns {
+			warnings := t.checkInsert(tree, expanded, options)
+			if t.strict && len(warnings) > 0 {
+				panic("muxie/trie#Insert: " + warnings[0].Error())
+			}
+
+			n := t.insertNode(tree, expanded, "", nil, nil)
+			if len(patterns) > 1 {
+				n.setOptional(pattern)
+			}
+			for _, opt := range options {
+				opt(n)
+			}
+			n.warnings = append(n.warnings, warnings...)
+		}
+	})
+}
+
+// Delete removes the node of the "pattern" from the trie, so it can no longer be found by `Search`.
+// Path patterns that differ only by their parameter names are the same for the trie,
+// i.e "/users/:id" and "/users/:name" delete the same node.
+// Nodes that are left without children are removed as well.
+//
+// A "pattern" with optional parts deletes the nodes of all of its expansions, see `Insert`.
+//
+// Returns false if the "pattern" was not registered.
+func (t *TrieOf[T]) Delete(pattern string) (deleted bool) {
+	if pattern == "" {
+		return false
+	}
+
+	patterns, err := expandOptional(pattern)
+	if err != nil {
+		return false
 	}
+
+	t.write(func(tree *trieTree[T]) {
+		for _, expanded := range patterns {
+			if t.deleteNode(tree, expanded) {
+				deleted = true
+			}
+		}
+	})
+
+	return
+}
+
+func (t *TrieOf[T]) deleteNode(tree *trieTree[T], pattern string) bool {
+	n := t.lookup(tree.root, slowPathSplit(pattern))
+	if n == nil || !n.end {
+		return false
+	}
+
+	n.reset()
+
+	// prune the nodes that are no longer part of a path.
+	for n != tree.root && !n.end && n.children.len() == 0 {
+		parent := n.parent
+		parent.removeChild(n)
+		n = parent
+	}
+
+	if t.compress {
+		compact(n)
+	}
+
+	tree.hasRootWildcard = tree.root.childWildcardParameter
+	tree.hasRootSlash = tree.root.hasChild(pathSep)
+	return true
+}
+
+// Replace removes the "pattern"'s node, if any, and inserts it back with the new "options",
+// unlike `Insert` which keeps the fields of an existing node that its "options" do not set.
+// Readers see either the old or the new node.
+func (t *TrieOf[T]) Replace(pattern string, options ...InsertOptionOf[T]) {
+	if pattern == "" {
+		panic("muxie/trie#Replace: empty pattern")
+	}
+
+	patterns := insertPatterns("Replace", pattern)
+	t.write(func(tree *trieTree[T]) {
+		for _, expanded := range patterns {
+			warnings := t.checkReplace(tree, expanded, options)
+			if t.strict && len(warnings) > 0 {
+				panic("muxie/trie#Replace: " + warnings[0].Error())
+			}
+
+			t.deleteNode(tree, expanded)
+			n := t.insertNode(tree, expanded, "", nil, nil)
+			if len(patterns) > 1 {
+				n.setOptional(pattern)
+			}
+			n.warnings = warnings
+			for _, opt := range options {
+				opt(n)
+			}
+		}
+	})
 }
 
 const (
@@ -170,27 +527,115 @@ func isSuffixParam(key string) bool {
 	return strings.Contains(key, SuffixParamStart)
 }
 
-func (t *Trie) insert(key, tag string, optionalData interface{}, handler http.Handler) *Node {
+// segmentKey returns the key which the path segment "s" of a pattern is stored under its parent node.
+func (t *TrieOf[T]) segmentKey(s string) string {
+	if isMixedParam(s) {
+		if m, err := parseMixedSegment(s, t.caseInsensitive); err == nil {
+			return m.key()
+		}
+	}
+
+	switch c := s[0]; {
+	case c == ParamStart[0]:
+		if _, constraintKey, constraint, err := parseParamConstraint(s[1:]); err == nil && constraint != nil {
+			return ParamStart + ParamConstraintStart + constraintKey + ParamConstraintEnd
+		}
+		return ParamStart
+	case c == WildcardParamStart[0]:
+		return WildcardParamStart
+	case isPrefixParam(s):
+		s = s[:strings.Index(s, PrefixParamStart)+len(PrefixParamStart)]
+	case isSuffixParam(s):
+		s = s[strings.Index(s, SuffixParamStart):]
+	}
+
+	if t.caseInsensitive {
+		s = strings.ToLower(s)
+	}
+
+	return s
+}
+
+func (t *TrieOf[T]) insert(key, tag string, optionalData interface{}, handler http.Handler) {
+	t.write(func(tree *trieTree[T]) {
+		t.insertNode(tree, key, tag, optionalData, handler)
+	})
+}
+
+func (t *TrieOf[T]) insertNode(tree *trieTree[T], key, tag string, optionalData interface{}, handler http.Handler) *NodeOf[T] {
 	input := slowPathSplit(key)
 
-	n := t.root
+	n := tree.root
 	if key == pathSep {
-		t.hasRootSlash = true
+		tree.hasRootSlash = true
 	}
 
 	var paramKeys []string
+	// the keys of the nodes from the root to the inserted one, to merge them back, see `Compress`.
+	var keys []string
+	if t.compress {
+		keys = make([]string, 0, len(input))
+	}
 
 	for i, s := range input {
 		c := s[0]
 		n.pathIndex = i + 1
 		n.paramCount = len(paramKeys)
 
+		if isMixedParam(s) {
+			m, err := parseMixedSegment(s, t.caseInsensitive)
+			if err != nil {
+				panic("muxie/trie#Insert: " + key + ": " + err.Error())
+			}
+
+			paramKeys = append(paramKeys, m.names()...)
+
+			// i.e ":{}.{}", stored separately from the named parameters, like the constrained ones.
+			s = m.key()
+			if !n.hasChild(s) {
+				child := new(NodeOf[T])
+				child.mixed = m
+				n.addChild(s, child, t.maxSortedChildren)
+				n.addMixedParameter(s, m)
+				n.hasDynamicChild = true
+			}
+
+			n = n.getChild(s)
+			if keys != nil {
+				keys = append(keys, s)
+			}
+			continue
+		}
+
 		if isParam, isWildcard, isPrefixParam, isSuffixParam := c == ParamStart[0], c == WildcardParamStart[0], isPrefixParam(s), isSuffixParam(s); isParam || isWildcard || isPrefixParam || isSuffixParam {
 			n.hasDynamicChild = true
 			var indx int
 
 			if isParam {
-				paramKeys = append(paramKeys, s[1:]) // without :
+				name, constraintKey, constraint, err := parseParamConstraint(s[1:]) // without :
+				if err != nil {
+					panic("muxie/trie#Insert: " + key + ": " + err.Error())
+				}
+
+				paramKeys = append(paramKeys, name)
+
+				if constraint != nil {
+					// i.e ":<int>", constrained parameters are stored separately from the ":"
+					// so "/users/:id<int>" and "/users/:slug" can live together.
+					s = ParamStart + ParamConstraintStart + constraintKey + ParamConstraintEnd
+					if !n.hasChild(s) {
+						child := new(NodeOf[T])
+						child.constraint = constraint
+						n.addChild(s, child, t.maxSortedChildren)
+						n.childConstrainedParameters = append(n.childConstrainedParameters, s)
+					}
+
+					n = n.getChild(s)
+					if keys != nil {
+						keys = append(keys, s)
+					}
+					continue
+				}
 
 				n.childNamedParameter = true
 				s = ParamStart
@@ -200,8 +645,8 @@ func (t *Trie) insert(key, tag string, optionalData interface{}, handler http.Ha
 
 				n.childWildcardParameter = true
 				s = WildcardParamStart
-				if t.root == n {
-					t.hasRootWildcard = true
+				if tree.root == n {
+					tree.hasRootWildcard = true
 				}
 
 			} else if isPrefixParam {
@@ -227,29 +672,50 @@ func (t *Trie) insert(key, tag string, optionalData interface{}, handler http.Ha
 		}
 
 		if !n.hasChild(s) {
-			child := NewNode()
-			n.addChild(s, child)
+			child := new(NodeOf[T])
+			n.addChild(s, child, t.maxSortedChildren)
 		}
 
 		n = n.getChild(s)
+		if n.tail != "" {
+			// the path may end or continue in the middle of a compressed node,
+			// it is split here and the nodes are merged back when the path is inserted.
+			n = n.split(s, t.maxSortedChildren)
+		}
+		if keys != nil {
+			keys = append(keys, s)
+		}
 	}
 
-	n.Tag = tag
-	n.Handler = handler
-	n.Data = optionalData
+	// an existing node keeps its data, so routes can be registered in steps, i.e per HTTP method,
+	// see `WithHandler` and `Replace`.
+	if tag != "" {
+		n.Tag = tag
+	}
+	if handler != nil {
+		n.Handler = handler
+	}
+	if data, ok := optionalData.(T); ok {
+		n.Data = data
+	}
 
 	n.paramKeys = paramKeys
 	n.key = key
+	n.optional = false
 	n.staticKey = resolveStaticPart(key)
 	n.end = true
 
+	if t.compress {
+		compactPath(n, keys)
+	}
+
 	return n
 }
 
 // SearchPrefix returns the last node which holds the key which starts with "prefix".
-func (t *Trie) SearchPrefix(prefix string) *Node {
+func (t *TrieOf[T]) SearchPrefix(prefix string) *NodeOf[T] {
 	input := slowPathSplit(prefix)
-	n := t.root
+	n := t.load().root
 
 	for i := 0; i < len(input); i++ {
 		s := input[i]
@@ -258,6 +724,14 @@ func (t *Trie) SearchPrefix(prefix string) *Node {
 		}
 		if child := n.getChild(s); child != nil {
 			n = child
+			if n.tail != "" {
+				// the prefix may end in the middle of a compressed node.
+				matched := t.matchTailSegments(n, input[i+1:])
+				if matched < n.levels()-1 && i+1+matched < len(input) {
+					return nil
+				}
+				i += matched
+			}
 			continue
 		}
 
@@ -268,7 +742,7 @@ func (t *Trie) SearchPrefix(prefix string) *Node {
 }
 
 // Parents returns the list of nodes that a node with "prefix" key belongs to.
-func (t *Trie) Parents(prefix string) (parents []*Node) {
+func (t *TrieOf[T]) Parents(prefix string) (parents []*NodeOf[T]) {
 	n := t.SearchPrefix(prefix)
 	if n != nil {
 		// without this node.
@@ -290,13 +764,13 @@ func (t *Trie) Parents(prefix string) (parents []*Node) {
 }
 
 // HasPrefix returns true if "prefix" is found inside the registered nodes.
-func (t *Trie) HasPrefix(prefix string) bool {
+func (t *TrieOf[T]) HasPrefix(prefix string) bool {
 	return t.SearchPrefix(prefix) != nil
 }
 
 // Autocomplete returns the keys that starts with "prefix",
 // this is useful for custom search-engines built on top of my trie implementation.
-func (t *Trie) Autocomplete(prefix string, sorter NodeKeysSorter) (list []string) {
+func (t *TrieOf[T]) Autocomplete(prefix string, sorter NodeKeysSorter) (list []string) {
 	n := t.SearchPrefix(prefix)
 	if n != nil {
 		list = n.Keys(sorter)
@@ -310,16 +784,6 @@ type ParamsSetter interface {
 	Set(string, string)
 }
 
-// Append a parameter value to the paramValues slice
-func appendParameterValue(paramValues *[]string, value string) {
-	if ln := len(*paramValues); cap(*paramValues) > ln {
-		*paramValues = (*paramValues)[:ln+1]
-		(*paramValues)[ln] = value
-	} else {
-		*paramValues = append(*paramValues, value)
-	}
-}
-
 // Helper function to return the minimum of two ints
 func min(a int, b int) int {
 	if a < b {
@@ -335,34 +799,68 @@ func min(a int, b int) int {
 // named parameters or wildcards.
 // Priority as:
 // 1. static paths
-// 2. named parameters with ":"
-// 3. wildcards
-// 4. fixed segments treated as named parameters (if searchUnvisitedParams == true)
-// 5. closest wildcard if not found, if any
-// 6. root wildcard
-func (t *Trie) Search(q string, params ParamsSetter) *Node {
+// 2. prefixed and suffixed parameters with "+:" and "-:"
+// 3. mixed parameters, i.e ":name.:ext", the ones with the longest literal text first
+// 4. constrained named parameters, i.e ":id<int>", in order of registration
+// 5. named parameters with ":"
+// 6. wildcards
+// 7. fixed segments treated as named parameters (if searchUnvisitedParams == true)
+// 8. closest wildcard if not found, if any
+// 9. root wildcard
+//
+// A path segment which passes a constraint but leads to no route is matched against the named parameter next to it,
+// i.e "/users/5/comments" is found by the "/users/:slug/comments" when "/users/:id<int>/posts" is registered too.
+//
+// See `TrieOptions.Backtracking` for a search that explores every child that a path segment matches.
+//
+// Search does not allocate, unless the path has more than 8 parameters,
+// the trie is `CaseInsensitive` and the path has upper case letters (its lower case copy)
+// or the "params" allocates to store them.
+// See `Explain` to trace the decisions of the Search for a specific path.
+func (t *TrieOf[T]) Search(q string, params ParamsSetter) *NodeOf[T] {
+	return t.search(q, params, nil)
+}
+
+// search is the implementation of the `Search`, the "trace" records its decisions if not nil, see `Explain`.
+func (t *TrieOf[T]) search(q string, params ParamsSetter, trace *ExplanationOf[T]) *NodeOf[T] {
+	tree := t.load()
 	end := len(q)
 
 	if end == 0 || (end == 1 && q[0] == pathSepB) {
 		// fixes only root wildcard but no / registered at.
-		if t.hasRootSlash {
-			return t.root.getChild(pathSep)
-		} else if t.hasRootWildcard {
+		if tree.hasRootSlash {
+			trace.fallback(MatchRootSlash, q)
+			return tree.root.getChild(pathSep)
+		} else if tree.hasRootWildcard {
 			// no need to going through setting parameters, this one has not but it is wildcard.
-			return t.root.getChild(WildcardParamStart)
+			trace.fallback(MatchRootWildcard, q)
+			return tree.root.getChild(WildcardParamStart)
 		}
 
+		trace.fallback(MatchNone, q)
 		return nil
 	}
 
-	n := t.root
+	if t.backtracking {
+		return t.searchBacktracking(tree, q, params, trace)
+	}
+
+	n := tree.root
 	start := 1
 	i := 1
-	var paramValues []string
-	visitedNodes := map[*Node]struct{}{}
+	// the scratch state lives on the stack, it allocates only if the path has more than 8 parameters.
+	var (
+		paramValuesBuf [8]string
+		paramValues    = paramValuesBuf[:0]
+		visited        visitedNodes[T]
+		// if > 0 then the search stopped inside the compressed node "n",
+		// after "partial" of its path segments, see `Compress`.
+		partial int
+	)
 
 	var qc string
 	if t.caseInsensitive {
+		// it allocates only if "q" has upper case letters.
 		qc = strings.ToLower(q)
 	} else {
 		qc = q
@@ -371,40 +869,74 @@ func (t *Trie) Search(q string, params ParamsSetter) *Node {
 	for {
 		if i == end || q[i] == pathSepB {
 			s := qc[start:i]
-			if child := n.getChild(s); child != nil {
+			// the node that the segment is matched against, inside a compressed node there is nothing to match.
+			from := n
+			if partial > 0 {
+				from = tree.empty
+			}
+
+			trace.segment(from, q[start:i], s)
+			if child := from.getChild(s); child != nil {
 				n = child
+				trace.match(MatchStatic, n)
+				if n.tail != "" {
+					var matched int
+					var ok bool
+					if i, matched, ok = matchTail(qc, i, n.tail); !ok {
+						partial = matched + 1
+					}
+					trace.tail(q[start:i])
+				}
 
-			} else if child, exists := n.getPrefixParamChild(s); exists {
+			} else if child, exists := from.getPrefixParamChild(s); exists {
 				n = child
-				visitedNodes[n] = struct{}{}
-				appendParameterValue(&paramValues, q[start:i])
+				visited.add(n)
+				paramValues = append(paramValues, q[start:i])
+				trace.match(MatchPrefixParam, n)
 
-			} else if child, exists := n.getSuffixParamChild(s); exists {
+			} else if child, exists := from.getSuffixParamChild(s); exists {
 				n = child
-				visitedNodes[n] = struct{}{}
-				appendParameterValue(&paramValues, q[start:i])
+				visited.add(n)
+				paramValues = append(paramValues, q[start:i])
+				trace.match(MatchSuffixParam, n)
 
-			} else if n.childNamedParameter {
-				n = n.getChild(ParamStart)
-				visitedNodes[n] = struct{}{}
-				appendParameterValue(&paramValues, q[start:i])
+			} else if child, values := from.getMixedParamChild(q[start:i], s, paramValues); child != nil {
+				n = child
+				visited.add(n)
+				paramValues = values
+				trace.match(MatchMixedParam, n)
 
-			} else if n.childWildcardParameter {
-				n = n.getChild(WildcardParamStart)
-				appendParameterValue(&paramValues, q[start:])
+			} else if child := from.getConstrainedParamChild(q[start:i]); child != nil {
+				n = child
+				visited.add(n)
+				paramValues = append(paramValues, q[start:i])
+				trace.match(MatchConstrainedParam, n)
+
+			} else if from.childNamedParameter {
+				n = from.getChild(ParamStart)
+				visited.add(n)
+				paramValues = append(paramValues, q[start:i])
+				trace.match(MatchNamedParam, n)
+
+			} else if from.childWildcardParameter {
+				n = from.getChild(WildcardParamStart)
+				paramValues = append(paramValues, q[start:])
+				trace.match(MatchWildcard, n)
 				break
 
 			} else {
-				var unvisited *Node
-				if t.searchUnvisitedParams {
-					unvisited, start, i = n.findClosestUnvisitedNode(visitedNodes, qc, i)
-				}
+				trace.match(MatchNone, nil)
+				// without the `SearchUnvisitedParams` only a constrained parameter falls back to the named one.
+				unvisited, ustart, ui := n.findClosestUnvisitedNode(&visited, q, qc, i, n.unvisitedLevels(partial), !t.searchUnvisitedParams)
 				if unvisited != nil {
+					start, i = ustart, ui
 					n = unvisited
-					visitedNodes[n] = struct{}{}
-					lim := min(n.paramCount, cap(paramValues))
-					paramValues = paramValues[:lim]
-					appendParameterValue(&paramValues, q[start:i])
+					partial = 0
+					visited.add(n)
+					// drop the values of the segments after the unvisited one.
+					paramValues = paramValues[:min(n.parent.paramCount, len(paramValues))]
+					paramValues = n.appendParamValues(q[start:i], qc[start:i], paramValues)
+					trace.backtrack(n, q[start:i])
 				} else {
 					n = n.findClosestParentWildcardNode()
 					if n != nil {
@@ -422,24 +954,35 @@ func (t *Trie) Search(q string, params ParamsSetter) *Node {
 						// /second/wild/static/otherstatic/
 						// req: /second/wild/static/otherstatic/random => but not found!
 						params.Set(n.paramKeys[0], q[len(n.staticKey):])
+						trace.fallback(MatchClosestWildcard, q[len(n.staticKey):])
 						return n
 					}
+					trace.fallback(MatchNone, "")
 					return nil
 
 				}
 			}
 
 			if i == end {
-				if t.searchUnvisitedParams && !n.end {
-					n, start, i = n.findClosestUnvisitedNode(visitedNodes, qc, i)
-					if n != nil {
-						visitedNodes[n] = struct{}{}
-						lim := min(n.paramCount, cap(paramValues))
-						paramValues = paramValues[:lim]
-						appendParameterValue(&paramValues, q[start:i])
-					}
-					if i == end {
-						break
+				if !n.end || partial > 0 {
+					// unlike a path segment that nothing matches, the last one is taken by the "n",
+					// so its siblings are checked first.
+					unvisited, ustart, ui := n.findClosestUnvisitedNode(&visited, q, qc, i, n.unvisitedLevels(partial)-1, !t.searchUnvisitedParams)
+					if unvisited != nil {
+						n, start, i, partial = unvisited, ustart, ui, 0
+						visited.add(n)
+						// drop the values of the segments after the unvisited one.
+						paramValues = paramValues[:min(n.parent.paramCount, len(paramValues))]
+						paramValues = n.appendParamValues(q[start:i], qc[start:i], paramValues)
+						trace.backtrack(n, q[start:i])
+						if i != end {
+							// go on with the path segments after the unvisited one.
+							i++
+							start = i
+							continue
+						}
+					} else if t.searchUnvisitedParams {
+						n = nil
 					}
 				}
 				break
@@ -453,26 +996,29 @@ func (t *Trie) Search(q string, params ParamsSetter) *Node {
 		i++
 	}
 
-	if n == nil || !n.end {
+	if n == nil || !n.end || partial > 0 {
 		if n != nil { // we need it on both places, on last segment (below) or on the first unnknown (above).
 			if n = n.findClosestParentWildcardNode(); n != nil {
 				params.Set(n.paramKeys[0], q[len(n.staticKey):])
+				trace.fallback(MatchClosestWildcard, q[len(n.staticKey):])
 				return n
 			}
 		}
 
-		if t.hasRootWildcard {
+		if tree.hasRootWildcard {
 			// that's the case for root wildcard, tests are passing
 			// even without it but stick with it for reference.
 			// Note ote that something like:
 			// Routes: /other2/*myparam and /other2/static
 			// Reqs: /other2/staticed will be handled
 			// by the /other2/*myparam and not the root wildcard (see above), which is what we want.
-			n = t.root.getChild(WildcardParamStart)
+			n = tree.root.getChild(WildcardParamStart)
 			params.Set(n.paramKeys[0], q[1:])
+			trace.fallback(MatchRootWildcard, q[1:])
 			return n
 		}
 
+		trace.fallback(MatchNone, "")
 		return nil
 	}
 
@@ -1,6 +1,11 @@
 package muxie
 
 import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -43,3 +48,171 @@ func BenchmarkTrieSearch(b *testing.B) {
 		}
 	}
 }
+
+// go test -run=XXX -v -bench=BenchmarkTrieSearchAllocs -count=3
+func BenchmarkTrieSearchAllocs(b *testing.B) {
+	b.Run("static", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrie(), []string{"/", "/users", "/users/list/all"}, "/users/list/all")
+	})
+	b.Run("params", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrie(), []string{"/users/:id<int>/files/:name", "/users/:name/files/img+:file"},
+			"/users/kataras/files/img_001.png")
+	})
+	b.Run("wildcard", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrie(), []string{"/static/*path", "/static/css/main.css"}, "/static/js/vendor/app.js")
+	})
+	b.Run("unvisited", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrieWithOptions(TrieOptions{SearchUnvisitedParams: true}),
+			[]string{"/a/b/c/z", "/a/:p1/c/d"}, "/a/b/c/d")
+	})
+	b.Run("case insensitive", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrieWithOptions(TrieOptions{CaseInsensitive: true}),
+			[]string{"/users/:id/profile"}, "/users/42/profile")
+	})
+	b.Run("case insensitive upper", func(b *testing.B) {
+		// the lower case copy of the path.
+		benchmarkTrieSearchAllocs(b, NewTrieWithOptions(TrieOptions{CaseInsensitive: true}),
+			[]string{"/users/:id/profile"}, "/Users/42/Profile", 1)
+	})
+	b.Run("mixed", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrieWithOptions(TrieOptions{SearchUnvisitedParams: true}),
+			[]string{"/files/:name.min.:ext", "/files/:name.:ext/:size<int>x:h<int>", "/files/a.b/info"}, "/files/a.b/640x480")
+	})
+	b.Run("compressed", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrieWithOptions(TrieOptions{Compress: true, SearchUnvisitedParams: true}),
+			[]string{"/api/v1/org/settings/billing", "/api/v1/org/:id/members", "/api/:version/org/settings/profile"},
+			"/api/v1/org/settings/profile")
+	})
+	b.Run("backtracking", func(b *testing.B) {
+		benchmarkTrieSearchNoAllocs(b, NewTrieWithOptions(TrieOptions{Backtracking: true, Compress: true}),
+			[]string{"/api/v1/org/settings/billing", "/api/v1/org/:id/members", "/api/:version/org/settings/profile"},
+			"/api/v1/org/settings/profile")
+	})
+}
+
+// benchmarkTrieSearchNoAllocs fails if the search of the "path" allocates.
+func benchmarkTrieSearchNoAllocs(b *testing.B, tree *Trie, patterns []string, path string) {
+	benchmarkTrieSearchAllocs(b, tree, patterns, path, 0)
+}
+
+// benchmarkTrieSearchAllocs fails if the search of the "path" does not allocate exactly "expected" times.
+func benchmarkTrieSearchAllocs(b *testing.B, tree *Trie, patterns []string, path string, expected float64) {
+	for _, pattern := range patterns {
+		tree.Insert(pattern, WithTag(pattern))
+	}
+
+	params := new(Writer)
+	search := func() {
+		if tree.Search(path, params) == nil {
+			b.Fatalf("%s: node not found", path)
+		}
+		params.reset(nil)
+	}
+
+	if allocs := testing.AllocsPerRun(100, search); allocs != expected {
+		b.Fatalf("%s: expected %v allocations but got %v per search", path, expected, allocs)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		search()
+	}
+}
+
+// benchmarkRoutes returns the route patterns of the muxie's server of the _benchmarks
+// and a request path for each of them, their parameters are set to "42".
+func benchmarkRoutes(b *testing.B) (patterns, paths []string) {
+	data, err := os.ReadFile(filepath.Join("_benchmarks", "muxie", "main.go"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, match := range regexp.MustCompile(`\.HandleFunc\("([^"]+)"`).FindAllSubmatch(data, -1) {
+		pattern := string(match[1])
+		segments := strings.Split(pattern, pathSep)
+		for i, segment := range segments {
+			if segment != "" && isParamSegment(segment) {
+				segments[i] = "42"
+			}
+		}
+
+		patterns = append(patterns, pattern)
+		paths = append(paths, strings.Join(segments, pathSep))
+	}
+
+	if len(patterns) == 0 {
+		b.Fatal("no routes found in the _benchmarks")
+	}
+
+	return
+}
+
+// go test -run=XXX -v -bench=BenchmarkTrieCompress -count=3
+//
+// The "maps" layout stores the children of every node in a map, like the previous versions,
+// it is the baseline of the sorted slices and of the compression.
+func BenchmarkTrieCompress(b *testing.B) {
+	patterns, paths := benchmarkRoutes(b)
+
+	layouts := []struct {
+		name              string
+		maxSortedChildren int
+		compress          bool
+	}{
+		{"maps", 0, false},
+		{"sorted", defaultMaxSortedChildren, false},
+		{"compressed", defaultMaxSortedChildren, true},
+	}
+
+	for _, layout := range layouts {
+		options := TrieOptions{Compress: layout.compress}
+		// the layout is chosen on insert.
+		newTree := func() *Trie {
+			tree := NewTrieWithOptions(options)
+			tree.maxSortedChildren = layout.maxSortedChildren
+			tree.Update(func(tx *Trie) {
+				for _, pattern := range patterns {
+					tx.Insert(pattern, WithTag(pattern))
+				}
+			})
+			return tree
+		}
+
+		b.Run(layout.name+"/memory", func(b *testing.B) {
+			var before, after runtime.MemStats
+			var total int64
+			for n := 0; n < b.N; n++ {
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				tree := newTree()
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				runtime.KeepAlive(tree)
+				// the heap may shrink between the reads, so the difference is signed.
+				total += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+			}
+
+			b.ReportMetric(float64(total)/float64(b.N)/float64(len(patterns)), "B/route")
+		})
+
+		b.Run(layout.name+"/search", func(b *testing.B) {
+			tree := newTree()
+			params := new(Writer)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				path := paths[n%len(paths)]
+				if tree.Search(path, params) == nil {
+					b.Fatalf("%s: node not found", path)
+				}
+				params.reset(nil)
+			}
+		})
+	}
+}
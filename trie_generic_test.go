@@ -0,0 +1,87 @@
+package muxie
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testRuleSet struct {
+	name  string
+	rules []string
+}
+
+func TestTrieOf(t *testing.T) {
+	tree := NewTrieOf[*testRuleSet]()
+	org := &testRuleSet{name: "org", rules: []string{"audit"}}
+	users := &testRuleSet{name: "users", rules: []string{"audit", "pii"}}
+
+	tree.Insert("/orgs/:org/*path", WithDataOf(org), WithTagOf[*testRuleSet]("org"))
+	tree.Insert("/orgs/:org/users/:id", WithDataOf(users))
+	tree.Insert("/orgs/:org/users/list")
+
+	params := new(Writer)
+	n := tree.Search("/orgs/acme/users/42", params)
+	if n == nil || n.Data != users {
+		t.Fatalf("expected the users rule set but got: %v", n)
+	}
+	if expected, got := []ParamEntry{{"org", "acme"}, {"id", "42"}}, params.GetAll(); !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected params: %v but got: %v", expected, got)
+	}
+
+	params.reset(nil)
+	if n = tree.Search("/orgs/acme/projects/1", params); n == nil || n.Data != org || n.Tag != "org" {
+		t.Fatalf("expected the org rule set but got: %v", n)
+	}
+
+	// a node without data has the zero value of the "T".
+	if n = tree.Search("/orgs/acme/users/list", new(Writer)); n == nil || n.Data != nil {
+		t.Fatalf("expected a node without data but got: %v", n)
+	}
+
+	tree.Insert("/teams/admins", WithDataOf(users))
+	tree.Insert("/teams/admins/members")
+	if n = tree.SearchPrefix("/teams/admins"); n == nil || n.Data != users {
+		t.Fatalf("expected the users rule set by prefix but got: %v", n)
+	}
+
+	if expected, got := []string{"/teams/admins", "/teams/admins/members"}, tree.Autocomplete("/teams", DefaultKeysSorter); !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected autocomplete: %v but got: %v", expected, got)
+	}
+
+	// the data is replaced and the node is reset on delete.
+	tree.Insert("/orgs/:org/users/:id", WithDataOf(org))
+	if n = tree.Search("/orgs/acme/users/42", new(Writer)); n == nil || n.Data != org {
+		t.Fatalf("expected the replaced rule set but got: %v", n)
+	}
+
+	if !tree.Delete("/orgs/:org/users/:id") {
+		t.Fatalf("expected the route to be deleted")
+	}
+	if n = tree.Search("/orgs/acme/users/42", new(Writer)); n == nil || n.Data != org {
+		t.Fatalf("expected the wildcard's rule set after delete but got: %v", n)
+	}
+}
+
+func TestTrieOfValues(t *testing.T) {
+	tree := NewTrieOfWithOptions[int](TrieOptions{CaseInsensitive: true, Compress: true})
+	tree.Update(func(tx *TrieOf[int]) {
+		tx.Insert("/api/v1/Limits", WithDataOf(100))
+		tx.Insert("/api/v1/limits/:tier", WithDataOf(10))
+	})
+
+	for path, expected := range map[string]int{"/API/v1/limits": 100, "/api/v1/limits/free": 10} {
+		n := tree.Search(path, new(Writer))
+		if n == nil || n.Data != expected {
+			t.Fatalf("%s: expected: %d but got: %v", path, expected, n)
+		}
+	}
+
+	var sum int
+	tree.Walk(func(n *NodeOf[int]) error {
+		sum += n.Data
+		return nil
+	})
+	if sum != 110 {
+		t.Fatalf("expected the data of all nodes to sum to 110 but got: %d", sum)
+	}
+}
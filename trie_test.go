@@ -1,8 +1,11 @@
 package muxie
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 type request struct {
@@ -113,8 +116,7 @@ func countParams(key string) int {
 	return strings.Count(key, ParamStart) + strings.Count(key, WildcardParamStart)
 }
 
-func testTrie(t *testing.T, oneByOne bool) {
-	tree := NewTrie()
+func testTrie(t *testing.T, tree *Trie, oneByOne bool) {
 	// insert.
 	for idx, tt := range tests {
 		if !oneByOne {
@@ -186,7 +188,343 @@ func testTrie(t *testing.T, oneByOne bool) {
 // TODO: same benchmark with different trie implementation vased on children with map[string]*trieNode instead (it should be even faster).
 func TestTrie(t *testing.T) {
 	t.Logf("Test when all nodes are registered\n")
-	testTrie(t, false)
+	testTrie(t, NewTrie(), false)
 	t.Logf("Test node one by one\n")
-	testTrie(t, true)
+	testTrie(t, NewTrie(), true)
+	t.Logf("Test compressed\n")
+	testTrie(t, NewTrieWithOptions(TrieOptions{Compress: true}), false)
+	testTrie(t, NewTrieWithOptions(TrieOptions{Compress: true}), true)
+	t.Logf("Test backtracking\n")
+	testTrie(t, NewTrieWithOptions(TrieOptions{Backtracking: true}), false)
+	testTrie(t, NewTrieWithOptions(TrieOptions{Backtracking: true, Compress: true}), true)
+}
+
+func TestTrieParamConstraints(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/users/:id<int>", WithTag("user_by_id"))
+	tree.Insert("/users/:slug", WithTag("user_by_slug"))
+	tree.Insert("/files/:name<regex([a-z]+\\.txt)>", WithTag("text_file"))
+	tree.Insert("/files/*path", WithTag("any_file"))
+	tree.Insert("/v/:ver<uuid>", WithTag("version"))
+	tree.Insert("/letters/:l<alpha>/:n<uint>", WithTag("letters"))
+
+	RegisterParamConstraint("even", func(arg string) (ParamConstraint, error) {
+		return func(value string) bool {
+			return isIntParam(value) && (value[len(value)-1]-'0')%2 == 0
+		}, nil
+	})
+	tree.Insert("/numbers/:n<even>", WithTag("even"))
+
+	tests := []struct {
+		path   string
+		tag    string
+		params map[string]string
+	}{
+		{"/users/42", "user_by_id", map[string]string{"id": "42"}},
+		{"/users/kataras", "user_by_slug", map[string]string{"slug": "kataras"}},
+		{"/files/readme.txt", "text_file", map[string]string{"name": "readme.txt"}},
+		{"/files/README.md", "any_file", map[string]string{"path": "README.md"}},
+		{"/files/docs/readme.txt", "any_file", map[string]string{"path": "docs/readme.txt"}},
+		{"/v/3f2504e0-4f89-11d3-9a0c-0305e82c3301", "version", map[string]string{"ver": "3f2504e0-4f89-11d3-9a0c-0305e82c3301"}},
+		{"/v/1.0.0", "", nil},
+		{"/letters/abc/12", "letters", map[string]string{"l": "abc", "n": "12"}},
+		{"/letters/abc/-12", "", nil},
+		{"/letters/a1/12", "", nil},
+		{"/numbers/42", "even", map[string]string{"n": "42"}},
+		{"/numbers/43", "", nil},
+	}
+
+	for i, tt := range tests {
+		params := new(Writer)
+		n := tree.Search(tt.path, params)
+		if tt.tag == "" {
+			if n != nil {
+				t.Fatalf("[%d] %s: expected to not be found but found: %s", i, tt.path, n.String())
+			}
+			continue
+		}
+
+		if n == nil {
+			t.Fatalf("[%d] %s: expected to be found", i, tt.path)
+		}
+
+		if expected, got := tt.tag, n.Tag; expected != got {
+			t.Fatalf("[%d] %s: expected tag: '%s' but got: '%s'", i, tt.path, expected, got)
+		}
+
+		if expected, got := len(tt.params), len(params.GetAll()); expected != got {
+			t.Fatalf("[%d] %s: expected params length: %d but got: %d", i, tt.path, expected, got)
+		}
+
+		for key, value := range tt.params {
+			if got := params.Get(key); got != value {
+				t.Fatalf("[%d] %s: expected param '%s' to be: '%s' but got: '%s'", i, tt.path, key, value, got)
+			}
+		}
+	}
+}
+
+func TestTrieParamConstraintsUnvisited(t *testing.T) {
+	// a path segment which passes the constraint falls back to the named parameter,
+	// with or without the `SearchUnvisitedParams`.
+	for _, tree := range []*Trie{NewTrie(), NewTrie().SearchUnvisitedParams(), NewTrie().Compress()} {
+		tree.Insert("/a/:id<int>/x", WithTag("int_x"))
+		tree.Insert("/a/:slug/y", WithTag("slug_y"))
+		tree.Insert("/users/:id<int>/posts", WithTag("posts"))
+		tree.Insert("/users/:slug/comments", WithTag("comments"))
+		tree.Insert("/files/:id<int>/meta/raw", WithTag("raw"))
+		tree.Insert("/files/:name/meta", WithTag("meta"))
+
+		tests := []struct {
+			path   string
+			tag    string
+			params []ParamEntry
+		}{
+			{"/a/5/y", "slug_y", []ParamEntry{{"slug", "5"}}},
+			{"/a/5/x", "int_x", []ParamEntry{{"id", "5"}}},
+			{"/users/5/comments", "comments", []ParamEntry{{"slug", "5"}}},
+			{"/users/5/posts", "posts", []ParamEntry{{"id", "5"}}},
+			// the constrained branch ends on a node which is not a route.
+			{"/files/5/meta", "meta", []ParamEntry{{"name", "5"}}},
+			{"/files/5/meta/raw", "raw", []ParamEntry{{"id", "5"}}},
+		}
+
+		for _, tt := range tests {
+			params := new(Writer)
+			n := tree.Search(tt.path, params)
+			if n == nil || n.Tag != tt.tag {
+				t.Fatalf("%s: expected to be found by: %s but got: %v", tt.path, tt.tag, n)
+			}
+
+			if got := params.GetAll(); !reflect.DeepEqual(tt.params, got) {
+				t.Fatalf("%s: expected params: %v but got: %v", tt.path, tt.params, got)
+			}
+		}
+
+		if n := tree.Search("/users/5/other", new(Writer)); n != nil {
+			t.Fatalf("expected to not be found but found: %s", n.Tag)
+		}
+	}
+}
+
+func TestTrieSearchUnvisitedParams(t *testing.T) {
+	tree := NewTrie().SearchUnvisitedParams()
+	tree.Insert("/a/b/c/z", WithTag("static"))
+	tree.Insert("/a/:p1/c/d", WithTag("p1"))
+	tree.Insert("/x/:a/b/c/z", WithTag("a"))
+	tree.Insert("/x/:a/:b/c/d", WithTag("a_b"))
+
+	tests := []struct {
+		path   string
+		tag    string
+		params []ParamEntry
+	}{
+		{"/a/b/c/d", "p1", []ParamEntry{{"p1", "b"}}},
+		{"/a/b/c/z", "static", nil},
+		{"/x/1/b/c/d", "a_b", []ParamEntry{{"a", "1"}, {"b", "b"}}},
+		{"/x/1/b/c/z", "a", []ParamEntry{{"a", "1"}}},
+	}
+
+	for _, tt := range tests {
+		params := new(Writer)
+		n := tree.Search(tt.path, params)
+		if n == nil || n.Tag != tt.tag {
+			t.Fatalf("%s: expected to be found by: %s but got: %v", tt.path, tt.tag, n)
+		}
+
+		if got := params.GetAll(); !reflect.DeepEqual(tt.params, got) && (len(tt.params) != 0 || len(got) != 0) {
+			t.Fatalf("%s: expected params: %v but got: %v", tt.path, tt.params, got)
+		}
+	}
+}
+
+func TestTrieParamConstraintsUnknown(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic for unknown parameter constraint")
+		}
+	}()
+
+	NewTrie().Insert("/users/:id<unknown>")
+}
+
+func TestTrieDelete(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/", WithTag("root"))
+	tree.Insert("/*path", WithTag("root_wildcard"))
+	tree.Insert("/users/:id", WithTag("user"))
+	tree.Insert("/users/:id/friends", WithTag("user_friends"))
+	tree.Insert("/users/:id<int>", WithTag("user_by_id"))
+	tree.Insert("/files/img+:name", WithTag("image"))
+	tree.Insert("/files/im+:name", WithTag("im"))
+	tree.Insert("/files/name-:.txt", WithTag("text"))
+
+	expect := func(path, tag string) {
+		t.Helper()
+		n := tree.Search(path, new(Writer))
+		if tag == "" {
+			if n != nil {
+				t.Fatalf("%s: expected to not be found but found: %s", path, n.String())
+			}
+			return
+		}
+
+		if n == nil || n.Tag != tag {
+			t.Fatalf("%s: expected to be found by node with tag: '%s'", path, tag)
+		}
+	}
+
+	expect("/files/img_001.png", "image")
+	expect("/files/imprint.png", "im")
+	expect("/files/notes.txt", "text")
+
+	if tree.Delete("/users/:id/unknown") {
+		t.Fatalf("expected Delete of an unknown pattern to return false")
+	}
+
+	if !tree.Delete("/users/:id") {
+		t.Fatalf("expected Delete of a registered pattern to return true")
+	}
+	// the node has a child, so it is kept but it is not valid anymore.
+	expect("/users/kataras", "root_wildcard")
+	expect("/users/42", "user_by_id")
+	expect("/users/kataras/friends", "user_friends")
+
+	tree.Delete("/users/:id<int>")
+	expect("/users/42", "root_wildcard")
+	if n := tree.SearchPrefix("/users"); len(n.childConstrainedParameters) != 0 || !n.childNamedParameter {
+		t.Fatalf("expected the constrained parameter child to be removed and the named one to be kept")
+	}
+
+	tree.Delete("/users/:id/friends")
+	if tree.HasPrefix("/users") {
+		t.Fatalf("expected the empty nodes to be pruned")
+	}
+
+	tree.Delete("/files/img+:name")
+	expect("/files/img_001.png", "im")
+	tree.Delete("/files/im+:name")
+	expect("/files/img_001.png", "root_wildcard")
+	expect("/files/notes.txt", "text")
+	if n := tree.SearchPrefix("/files"); n.childPrefixParameter || len(n.childPrefixLengths) != 0 {
+		t.Fatalf("expected the prefix parameter flags and lengths to be cleared")
+	}
+
+	tree.Delete("/files/name-:.txt")
+	if tree.HasPrefix("/files") {
+		t.Fatalf("expected the empty nodes to be pruned")
+	}
+
+	tree.Delete("/*path")
+	if tree.load().hasRootWildcard {
+		t.Fatalf("expected root wildcard to be removed")
+	}
+	expect("/files/img_001.png", "")
+	expect("/", "root")
+
+	tree.Delete("/")
+	if tree.load().hasRootSlash {
+		t.Fatalf("expected root slash to be removed")
+	}
+	expect("/", "")
+
+	tree.Insert("/users/:id", WithTag("user"))
+	tree.Replace("/users/:id", WithTag("user_replaced"))
+	expect("/users/42", "user_replaced")
+}
+
+func TestTrieUpdate(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/first", WithTag("first"))
+	// the next writes should not touch the nodes that are returned to the readers.
+	first := tree.Search("/first", new(Writer))
+
+	tree.Update(func(tx *Trie) {
+		tx.Insert("/second", WithTag("second"))
+		tx.Delete("/first")
+
+		if tree.Search("/second", new(Writer)) != nil {
+			t.Fatalf("expected the changes of an update to not be visible before its end")
+		}
+	})
+
+	if tree.Search("/first", new(Writer)) != nil {
+		t.Fatalf("expected /first to be deleted")
+	}
+	if n := tree.Search("/second", new(Writer)); n == nil || n.Tag != "second" {
+		t.Fatalf("expected /second to be inserted")
+	}
+	if !first.IsEnd() || first.Tag != "first" {
+		t.Fatalf("expected the node that was already searched to be kept untouched")
+	}
+}
+
+func TestTrieReadersDoNotBlock(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/first")
+
+	// the first search of the trie happens while a writer holds it.
+	tree.Update(func(tx *Trie) {
+		tx.Insert("/second")
+
+		found := make(chan bool)
+		go func() {
+			found <- tree.Search("/first", new(Writer)) != nil
+		}()
+
+		select {
+		case ok := <-found:
+			if !ok {
+				t.Fatalf("expected /first to be found during the update")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the search to not wait for the update")
+		}
+	})
+}
+
+func TestTrieFirstSearchDuringInserts(t *testing.T) {
+	tree := NewTrie()
+	tree.Insert("/first")
+
+	// the trie is not searched yet, so the inserts reuse the previous tree
+	// until the reader below loads one.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if tree.Search("/first", new(Writer)) == nil {
+				t.Errorf("expected /first to be found while inserting")
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		tree.Insert(fmt.Sprintf("/items/%d", i))
+	}
+	<-done
+
+	for i := 0; i < 100; i++ {
+		if tree.Search(fmt.Sprintf("/items/%d", i), new(Writer)) == nil {
+			t.Fatalf("expected /items/%d to be found", i)
+		}
+	}
+}
+
+func TestTrieUpdateOptions(t *testing.T) {
+	tree := NewTrieWithOptions(TrieOptions{Backtracking: true})
+	tree.Insert("/files/:id<int>/meta")
+	tree.Insert("/files/:name/raw")
+
+	tree.Update(func(tx *Trie) {
+		if expected, got := tree.options(), tx.options(); expected != got {
+			t.Fatalf("expected the options: %#v but got: %#v", expected, got)
+		}
+
+		// the search of the update explores every child as well.
+		if tx.Search("/files/5/raw", new(Writer)) == nil {
+			t.Fatalf("expected the update to search with backtracking")
+		}
+	})
 }
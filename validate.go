@@ -0,0 +1,268 @@
+package muxie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errStopWalk stops a walk of the nodes, the first visited node is the one with the smallest path segments.
+var errStopWalk = errors.New("stop")
+
+// RouteWarning describes an ambiguous route, see `Trie#Validate` and `Trie#Strict`.
+type RouteWarning struct {
+	// Pattern is the path pattern of the route.
+	Pattern string
+	// Conflict is the path pattern of the route that the Pattern conflicts with, if any.
+	Conflict string
+	// Reason describes the problem, i.e "duplicate route".
+	Reason string
+}
+
+// Error returns the description of the warning, i.e
+// `route "/a/:y" conflicts with "/a/:x": parameter ":y" is named ":x" by the other route`.
+func (w RouteWarning) Error() string {
+	if w.Conflict == "" {
+		return fmt.Sprintf("route %q: %s", w.Pattern, w.Reason)
+	}
+
+	return fmt.Sprintf("route %q conflicts with %q: %s", w.Pattern, w.Conflict, w.Reason)
+}
+
+// Validate returns the warnings about the ambiguous routes of the trie, in order of `Walk`.
+// The warnings are collected when the routes are inserted, the `Strict` mode panics on them instead.
+func (t *TrieOf[T]) Validate() (warnings []RouteWarning) {
+	t.Walk(func(n *NodeOf[T]) error {
+		warnings = append(warnings, n.warnings...)
+		return nil
+	})
+
+	return
+}
+
+// TryInsert is like `Insert` but it returns the first warning as an error
+// and it does not insert the route if the "pattern" is ambiguous, even if the trie is not `Strict`.
+func (t *TrieOf[T]) TryInsert(pattern string, options ...InsertOptionOf[T]) (err error) {
+	if pattern == "" {
+		return fmt.Errorf("muxie: empty pattern")
+	}
+
+	patterns, err := expandOptional(pattern)
+	if err != nil {
+		return fmt.Errorf("muxie: %w", err)
+	}
+
+	t.write(func(tree *trieTree[T]) {
+		for _, expanded := range patterns {
+			if warnings := t.checkInsert(tree, expanded, options); len(warnings) > 0 {
+				err = fmt.Errorf("muxie: %w", warnings[0])
+				return
+			}
+		}
+
+		for _, expanded := range patterns {
+			n := t.insertNode(tree, expanded, "", nil, nil)
+			if len(patterns) > 1 {
+				n.setOptional(pattern)
+			}
+			for _, opt := range options {
+				opt(n)
+			}
+		}
+	})
+
+	return
+}
+
+// Strict makes the registration of an ambiguous route panic with a descriptive error, see `Trie#Strict`.
+// It affects the sub muxes too, as they share the same `Routes`.
+func (m *Mux) Strict() *Mux {
+	m.Routes.Strict()
+	return m
+}
+
+// Validate returns the warnings about the ambiguous routes of this Mux,
+// for a sub mux it returns only the ones under its path prefix. See `Trie#Validate`.
+//
+// Useful to fail a CI run, i.e:
+//
+//	for _, w := range mux.Validate() {
+//		t.Error(w)
+//	}
+func (m *Mux) Validate() (warnings []RouteWarning) {
+	tables := []*Trie{m.Routes}
+	m.hosts.each(func(e *hostEntry) {
+		tables = append(tables, e.routes)
+	})
+
+	for _, table := range tables {
+		for _, w := range table.Validate() {
+			if m.root == "" || w.Pattern == m.root || strings.HasPrefix(w.Pattern, m.root+pathSep) {
+				warnings = append(warnings, w)
+			}
+		}
+	}
+
+	return
+}
+
+// checkInsert returns the warnings of inserting the "pattern" with the "options" to the "tree".
+// It does not modify the tree.
+func (t *TrieOf[T]) checkInsert(tree *trieTree[T], pattern string, options []InsertOptionOf[T]) []RouteWarning {
+	return t.check(tree, pattern, options, false)
+}
+
+// checkReplace is like `checkInsert` but the existing route of the "pattern", if any, is not taken into account.
+func (t *TrieOf[T]) checkReplace(tree *trieTree[T], pattern string, options []InsertOptionOf[T]) []RouteWarning {
+	return t.check(tree, pattern, options, true)
+}
+
+func (t *TrieOf[T]) check(tree *trieTree[T], pattern string, options []InsertOptionOf[T], replace bool) (warnings []RouteWarning) {
+	warn := func(conflict, format string, args ...interface{}) {
+		warnings = append(warnings, RouteWarning{Pattern: pattern, Conflict: conflict, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	segments := slowPathSplit(pattern)
+
+	// the existing node of the pattern, it is skipped when the pattern replaces it.
+	var target *NodeOf[T]
+	if replace {
+		target = t.lookup(tree.root, segments)
+	}
+
+	n := tree.root
+	paramIndex := 0
+	// the static path segments of the compressed node "n" that are left to check.
+	tailLevels := 0
+	for i, s := range segments {
+		isWildcard := s[0] == WildcardParamStart[0]
+		if isWildcard && i < len(segments)-1 {
+			warn("", "wildcard %q is not the last path segment, the next segments are never matched", s)
+		}
+
+		if n == nil {
+			continue
+		}
+
+		if tailLevels > 0 {
+			tailLevels--
+			continue
+		}
+
+		key := t.segmentKey(s)
+		switch {
+		case s[0] == ParamStart[0] || isWildcard || isMixedParam(s):
+		case isPrefixParam(s):
+			for _, other := range n.childKeys() {
+				child := n.getChild(other)
+				if other == key || child.mixed != nil {
+					continue
+				}
+
+				if isPrefixParam(other) {
+					prefix, otherPrefix := strings.TrimSuffix(key, PrefixParamStart), strings.TrimSuffix(other, PrefixParamStart)
+					if strings.HasPrefix(prefix, otherPrefix) || strings.HasPrefix(otherPrefix, prefix) {
+						warn(anyRoutePattern(child, target), "prefix parameter %q shadows the prefix parameter %q", key, other)
+					}
+				} else if strings.HasPrefix(other, SuffixParamStart) {
+					warn(anyRoutePattern(child, target), "prefix parameter %q shadows the suffix parameter %q", key, other)
+				}
+			}
+		case isSuffixParam(s):
+			for _, other := range n.childKeys() {
+				child := n.getChild(other)
+				if other == key || child.mixed != nil {
+					continue
+				}
+
+				if strings.HasPrefix(other, SuffixParamStart) {
+					suffix, otherSuffix := strings.TrimPrefix(key, SuffixParamStart), strings.TrimPrefix(other, SuffixParamStart)
+					if strings.HasSuffix(suffix, otherSuffix) || strings.HasSuffix(otherSuffix, suffix) {
+						warn(anyRoutePattern(child, target), "suffix parameter %q shadows the suffix parameter %q", key, other)
+					}
+				} else if isPrefixParam(other) {
+					warn(anyRoutePattern(child, target), "suffix parameter %q is shadowed by the prefix parameter %q", key, other)
+				}
+			}
+		}
+
+		n = n.getChild(key)
+		if n != nil && n.tail != "" {
+			// the pattern ends or continues in the middle of a compressed node, none of its nodes exists.
+			if tailLevels = n.levels() - 1; t.matchTailSegments(n, segments[i+1:]) != tailLevels {
+				n = nil
+			}
+		}
+
+		if !isParamSegment(s) {
+			continue
+		}
+
+		// a segment with mixed parameters has more than one.
+		params := routeParams(pathSep + s)
+		if n != nil {
+			for j, p := range params {
+				if otherName, other := routeParamName(n, paramIndex+j, target); other != "" && otherName != p.Name {
+					warn(other, "parameter %q of the segment %q is named %q by the other route", p.Name, s, otherName)
+				}
+			}
+		}
+		paramIndex += len(params)
+	}
+
+	if n == nil || !n.end || n == target {
+		return
+	}
+
+	// the same node, check if the options set something that the node already has.
+	probe := new(NodeOf[T])
+	for _, opt := range options {
+		opt(probe)
+	}
+
+	switch {
+	case probe.Handler != nil && n.Handler != nil:
+		warn(n.key, "duplicate route, the new handler replaces the existing one")
+	case len(probe.matchedHandlers) > 0:
+		// conditional handlers are added to the existing ones.
+	case probe.Handler == nil && len(probe.methodHandlers) == 0:
+		warn(n.key, "duplicate route")
+	default:
+		for _, method := range probe.Methods() {
+			if _, exists := n.methodHandlers[method]; exists {
+				warn(n.key, "duplicate route for the %s method, the new handler replaces the existing one", method)
+			}
+		}
+	}
+
+	return
+}
+
+// routeParamName returns the name of the parameter at "paramIndex" and the pattern of a route under the "n",
+// the "n" is the node of that parameter. The "skip" node is ignored.
+func routeParamName[T any](n *NodeOf[T], paramIndex int, skip *NodeOf[T]) (name, pattern string) {
+	walkNode(n, func(end *NodeOf[T]) error {
+		if end == skip || paramIndex >= len(end.paramKeys) {
+			return nil
+		}
+
+		name, pattern = end.paramKeys[paramIndex], end.key
+		return errStopWalk
+	})
+
+	return
+}
+
+// anyRoutePattern returns the pattern of a route under the "n", the "skip" node is ignored.
+func anyRoutePattern[T any](n *NodeOf[T], skip *NodeOf[T]) (pattern string) {
+	walkNode(n, func(end *NodeOf[T]) error {
+		if end == skip {
+			return nil
+		}
+
+		pattern = end.key
+		return errStopWalk
+	})
+
+	return
+}
@@ -0,0 +1,175 @@
+package muxie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrieStrict(t *testing.T) {
+	handler := WithHandler(http.NotFoundHandler())
+
+	tests := []struct {
+		existing []string
+		pattern  string
+		panics   string
+	}{
+		{[]string{"/a/:x"}, "/a/:y", `route "/a/:y" conflicts with "/a/:x": parameter "y" of the segment ":y" is named "x" by the other route`},
+		{[]string{"/a/:x/b"}, "/a/:y/c", `route "/a/:y/c" conflicts with "/a/:x/b": parameter "y"`},
+		{[]string{"/files/*path"}, "/files/*file", `parameter "file" of the segment "*file" is named "path"`},
+		{[]string{"/a"}, "/a", `route "/a" conflicts with "/a": duplicate route, the new handler replaces the existing one`},
+		{nil, "/files/*path/edit", `route "/files/*path/edit": wildcard "*path" is not the last path segment`},
+		{[]string{"/images/img+:name"}, "/images/im+:name", `prefix parameter "im+:" shadows the prefix parameter "img+:"`},
+		{[]string{"/docs/name-:.gz"}, "/docs/name-:.tar.gz", `suffix parameter "-:.tar.gz" shadows the suffix parameter "-:.gz"`},
+		{[]string{"/docs/name-:.txt"}, "/docs/img+:name", `prefix parameter "img+:" shadows the suffix parameter "-:.txt"`},
+		// no conflicts.
+		{[]string{"/a/:x/b"}, "/a/:x/c", ""},
+		{[]string{"/users/:id<int>"}, "/users/:name", ""},
+		{[]string{"/images/img+:name"}, "/images/thumb+:name", ""},
+		{[]string{"/a/b"}, "/a/:x", ""},
+	}
+
+	for i, tt := range tests {
+		tree := NewTrieWithOptions(TrieOptions{Strict: true})
+		for _, pattern := range tt.existing {
+			tree.Insert(pattern, handler)
+		}
+
+		panicked := catchPanic(func() {
+			tree.Insert(tt.pattern, handler)
+		})
+
+		if tt.panics == "" {
+			if panicked != "" {
+				t.Fatalf("[%d] %s: expected no panic but got: %s", i, tt.pattern, panicked)
+			}
+			continue
+		}
+
+		if !strings.Contains(panicked, tt.panics) {
+			t.Fatalf("[%d] %s: expected panic to contain: %s\nbut got: %s", i, tt.pattern, tt.panics, panicked)
+		}
+
+		// the strict insert does not modify the trie.
+		if warnings := tree.Validate(); len(warnings) != 0 {
+			t.Fatalf("[%d] %s: expected no warnings but got: %v", i, tt.pattern, warnings)
+		}
+
+		// not strict: it is inserted and reported by Validate.
+		tree = NewTrie()
+		for _, pattern := range tt.existing {
+			tree.Insert(pattern, handler)
+		}
+		tree.Insert(tt.pattern, handler)
+
+		if warnings := tree.Validate(); len(warnings) == 0 || !strings.Contains(warnings[0].Error(), tt.panics) {
+			t.Fatalf("[%d] %s: expected a warning which contains: %s\nbut got: %v", i, tt.pattern, tt.panics, warnings)
+		}
+	}
+
+	tree := NewTrie()
+	if err := tree.TryInsert("/files/*path/edit", handler); err == nil || !strings.Contains(err.Error(), "is not the last path segment") {
+		t.Fatalf("expected a wildcard error but got: %v", err)
+	}
+	if tree.Search("/files/readme/edit", nil) != nil {
+		t.Fatalf("expected the route to not be inserted")
+	}
+}
+
+func TestMuxValidate(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	mux := NewMux().Strict()
+	mux.Get("/users/:id", noop)
+	mux.Delete("/users/:id", noop)
+	mux.Handle("/users/:id", http.HandlerFunc(noop))
+
+	if panicked := catchPanic(func() { mux.Get("/users/:id", noop) }); !strings.Contains(panicked, "duplicate route for the GET method") {
+		t.Fatalf("expected a duplicate GET route panic but got: %s", panicked)
+	}
+
+	// the replaced route is not a conflict.
+	mux.Routes.Replace("/users/:name", WithHandler(http.HandlerFunc(noop)))
+	if panicked := catchPanic(func() { mux.HandleFunc("/users/:name", noop) }); !strings.Contains(panicked, "duplicate route, the new handler replaces the existing one") {
+		t.Fatalf("expected a duplicate route panic but got: %s", panicked)
+	}
+
+	mux = NewMux()
+	v1 := mux.Of("/v1")
+	v1.HandleFunc("/users/:id", noop)
+	v1.HandleFunc("/users/:name/friends", noop)
+	mux.HandleFunc("/about", noop)
+	mux.HandleFunc("/about", noop)
+
+	expected := []RouteWarning{
+		{Pattern: "/about", Conflict: "/about", Reason: "duplicate route, the new handler replaces the existing one"},
+		{Pattern: "/v1/users/:name/friends", Conflict: "/v1/users/:id", Reason: `parameter "name" of the segment ":name" is named "id" by the other route`},
+	}
+
+	warnings := mux.Validate()
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected warnings: %v but got: %v", expected, warnings)
+	}
+	for i := range expected {
+		if expected[i] != warnings[i] {
+			t.Fatalf("[%d] expected warning: %#v but got: %#v", i, expected[i], warnings[i])
+		}
+	}
+
+	if warnings = v1.(*Mux).Validate(); len(warnings) != 1 || warnings[0] != expected[1] {
+		t.Fatalf("expected only the warning of the sub mux but got: %v", warnings)
+	}
+}
+
+func TestMuxValidateDuplicate(t *testing.T) {
+	handler := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}
+	}
+
+	mux := NewMux()
+	mux.HandleFunc("/about", handler("first"))
+	mux.HandleFunc("/about", handler("second"))
+	mux.Get("/users/:id", handler("first GET"))
+	mux.Get("/users/:id", handler("second GET"))
+
+	tests := []struct {
+		path   string
+		body   string
+		reason string
+	}{
+		{"/about", "second", "duplicate route, the new handler replaces the existing one"},
+		{"/users/42", "second GET", "duplicate route for the GET method, the new handler replaces the existing one"},
+	}
+
+	warnings := mux.Validate()
+	if len(warnings) != len(tests) {
+		t.Fatalf("expected %d warnings but got: %v", len(tests), warnings)
+	}
+
+	for i, tt := range tests {
+		if warnings[i].Reason != tt.reason {
+			t.Fatalf("[%d] expected the reason: %q but got: %q", i, tt.reason, warnings[i].Reason)
+		}
+
+		// the warning should describe the handler which serves the route.
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if body := w.Body.String(); body != tt.body {
+			t.Fatalf("[%d] expected the route to be served by: %q but got: %q", i, tt.body, body)
+		}
+	}
+}
+
+func catchPanic(fn func()) (msg string) {
+	defer func() {
+		if v := recover(); v != nil {
+			msg = v.(string)
+		}
+	}()
+
+	fn()
+	return
+}
@@ -0,0 +1,589 @@
+package muxie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// yamlValue is a JSON value which keeps the order of its object keys.
+type yamlValue struct {
+	// scalar is the JSON text of a string, number, boolean or null value.
+	scalar   string
+	isString bool
+
+	isObject bool
+	keys     []string
+
+	isArray bool
+	// the values of the object keys or the array items.
+	values []*yamlValue
+}
+
+// jsonToYAML converts a JSON document to YAML, the object keys keep their order.
+// It is used to serve YAML documents without an external dependency, see `OpenAPIDocument#YAML`.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := readYAMLValue(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	switch {
+	case v.isObject && len(v.keys) > 0:
+		writeYAMLObject(&b, v, 0)
+	case v.isArray && len(v.values) > 0:
+		writeYAMLArray(&b, v, 0)
+	default:
+		b.WriteString(v.inline())
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes(), nil
+}
+
+func readYAMLValue(dec *json.Decoder) (*yamlValue, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	v := new(yamlValue)
+	switch t := tok.(type) {
+	case json.Delim:
+		v.isObject, v.isArray = t == '{', t == '['
+		for dec.More() {
+			if v.isObject {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v.keys = append(v.keys, key.(string))
+			}
+
+			child, err := readYAMLValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			v.values = append(v.values, child)
+		}
+
+		// the closing delimiter.
+		if _, err = dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		v.scalar, v.isString = t, true
+	case json.Number:
+		v.scalar = t.String()
+	case bool:
+		v.scalar = fmt.Sprint(t)
+	case nil:
+		v.scalar = "null"
+	}
+
+	return v, nil
+}
+
+// inline returns the value as it is written after a "key: " or a "- ",
+// it is only called for scalars and empty objects or arrays.
+func (v *yamlValue) inline() string {
+	switch {
+	case v.isObject:
+		return "{}"
+	case v.isArray:
+		return "[]"
+	case v.isString:
+		return yamlString(v.scalar)
+	default:
+		return v.scalar
+	}
+}
+
+func (v *yamlValue) isBlock() bool {
+	return len(v.values) > 0
+}
+
+func writeYAMLObject(b *bytes.Buffer, v *yamlValue, indent int) {
+	for i, key := range v.keys {
+		if i > 0 || b.Len() == 0 || b.Bytes()[b.Len()-1] == '\n' {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		b.WriteString(yamlString(key))
+		b.WriteByte(':')
+		writeYAMLChild(b, v.values[i], indent)
+	}
+}
+
+func writeYAMLArray(b *bytes.Buffer, v *yamlValue, indent int) {
+	for i, item := range v.values {
+		if i > 0 || b.Len() == 0 || b.Bytes()[b.Len()-1] == '\n' {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		b.WriteByte('-')
+		if item.isObject && item.isBlock() {
+			// the first key is written next to the "- ".
+			b.WriteByte(' ')
+			writeYAMLObject(b, item, indent+2)
+			continue
+		}
+		writeYAMLChild(b, item, indent)
+	}
+}
+
+// writeYAMLChild writes the value of an object key or an array item, "indent" is the indentation of its parent.
+func writeYAMLChild(b *bytes.Buffer, v *yamlValue, indent int) {
+	if !v.isBlock() {
+		b.WriteByte(' ')
+		b.WriteString(v.inline())
+		b.WriteByte('\n')
+		return
+	}
+
+	b.WriteByte('\n')
+	if v.isObject {
+		writeYAMLObject(b, v, indent+2)
+	} else {
+		writeYAMLArray(b, v, indent+2)
+	}
+}
+
+// yamlString returns "s" as it is, if it is a safe plain scalar, otherwise double-quoted.
+func yamlString(s string) string {
+	if isPlainYAMLString(s) {
+		return s
+	}
+
+	// JSON strings are valid YAML double-quoted scalars.
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func isPlainYAMLString(s string) bool {
+	if s == "" || s[0] == ' ' || s[len(s)-1] == ' ' {
+		return false
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return false
+	}
+
+	// numbers, dates and the like.
+	if c := s[0]; c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '-' || c == '.' || c == '/' || c == ' ' || c == '(' || c == ')' || c == ',') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// yamlLine is a line of a YAML document without its indentation and its comment.
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlToJSON converts a YAML document to JSON, the object keys keep their order.
+// It reads the block mappings and sequences, the plain, single and double-quoted scalars
+// and the flow sequences and mappings on a single line, enough for configuration files without an external dependency,
+// see `ParseRoutesConfig`. Anchors, tags, multi-line scalars, complex keys and multiple documents are not supported.
+func yamlToJSON(data []byte) ([]byte, error) {
+	lines, err := readYAMLLines(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &yamlValue{scalar: "null"}
+	if len(lines) > 0 {
+		p := yamlParser{lines: lines}
+		if v, err = p.block(lines[0].indent); err != nil {
+			return nil, err
+		}
+		if p.i < len(lines) {
+			return nil, p.errorf("unexpected indentation")
+		}
+	}
+
+	var b bytes.Buffer
+	writeJSONValue(&b, v)
+	return b.Bytes(), nil
+}
+
+func readYAMLLines(data []byte) ([]yamlLine, error) {
+	var (
+		lines []yamlLine
+		// if true then the document ended with a "...".
+		ended bool
+	)
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(stripYAMLComment(text), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+
+		// the markers of a single document.
+		if trimmed == "---" || trimmed == "..." {
+			if len(lines) > 0 && trimmed == "---" {
+				return nil, fmt.Errorf("yaml: line %d: multiple documents are not supported", i+1)
+			}
+			ended = trimmed == "..."
+			continue
+		}
+
+		if ended {
+			return nil, fmt.Errorf("yaml: line %d: multiple documents are not supported", i+1)
+		}
+
+		if trimmed[0] == '\t' {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+
+		if trimmed == "?" || strings.HasPrefix(trimmed, "? ") {
+			return nil, fmt.Errorf("yaml: line %d: complex mapping keys are not supported", i+1)
+		}
+
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+
+	return lines, nil
+}
+
+// stripYAMLComment removes the comment of the "line", a "#" at its start or after a space, outside of quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	line := p.lines[len(p.lines)-1].number
+	if p.i < len(p.lines) {
+		line = p.lines[p.i].number
+	}
+
+	return fmt.Errorf("yaml: line %d: "+format, append([]interface{}{line}, args...)...)
+}
+
+// block reads the mapping, the sequence or the scalar which starts at the current line, its lines have the "indent".
+func (p *yamlParser) block(indent int) (*yamlValue, error) {
+	line := p.lines[p.i]
+	if isYAMLSequenceItem(line.text) {
+		return p.sequence(indent)
+	}
+
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.mapping(indent)
+	}
+
+	p.i++
+	return parseYAMLScalar(line.text)
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) sequence(indent int) (*yamlValue, error) {
+	v := &yamlValue{isArray: true}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && isYAMLSequenceItem(p.lines[p.i].text) {
+		line := p.lines[p.i]
+		rest := strings.TrimLeft(line.text[1:], " ")
+
+		var (
+			item *yamlValue
+			err  error
+		)
+		switch {
+		case rest == "":
+			item, err = p.child(indent, false)
+		default:
+			if _, _, ok := splitYAMLKey(rest); ok || isYAMLSequenceItem(rest) {
+				// a mapping or a sequence which starts next to the "- ", its lines are indented to its first key.
+				p.lines[p.i] = yamlLine{number: line.number, indent: line.indent + len(line.text) - len(rest), text: rest}
+				item, err = p.block(p.lines[p.i].indent)
+			} else {
+				p.i++
+				item, err = parseYAMLScalar(rest)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		v.values = append(v.values, item)
+	}
+
+	return v, nil
+}
+
+func (p *yamlParser) mapping(indent int) (*yamlValue, error) {
+	v := &yamlValue{isObject: true}
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		key, rest, ok := splitYAMLKey(p.lines[p.i].text)
+		if !ok {
+			return nil, p.errorf("expected a mapping key")
+		}
+		for _, k := range v.keys {
+			if k == key {
+				return nil, p.errorf("duplicate key %q", key)
+			}
+		}
+
+		var (
+			value *yamlValue
+			err   error
+		)
+		if rest == "" {
+			// the sequence of a key may have the same indentation as the key.
+			value, err = p.child(indent, true)
+		} else {
+			p.i++
+			value, err = parseYAMLScalar(rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		v.keys = append(v.keys, key)
+		v.values = append(v.values, value)
+	}
+
+	return v, nil
+}
+
+// child reads the block under the current line, which has the "indent", null if there is none.
+func (p *yamlParser) child(indent int, sequenceSameIndent bool) (*yamlValue, error) {
+	p.i++
+	if p.i < len(p.lines) {
+		next := p.lines[p.i]
+		if next.indent > indent || sequenceSameIndent && next.indent == indent && isYAMLSequenceItem(next.text) {
+			return p.block(next.indent)
+		}
+	}
+
+	return &yamlValue{scalar: "null"}, nil
+}
+
+// splitYAMLKey splits a "key: value" line, the "value" is empty if the value is on the next lines.
+func splitYAMLKey(text string) (key, value string, ok bool) {
+	end := -1
+	if c := text[0]; c == '"' || c == '\'' {
+		end = yamlQuotedEnd(text)
+		if end == -1 || end+1 >= len(text) || text[end+1] != ':' {
+			return "", "", false
+		}
+		end++
+	} else if c == '[' || c == '{' {
+		return "", "", false
+	} else {
+		for i := 0; i < len(text); i++ {
+			if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+				end = i
+				break
+			}
+		}
+	}
+
+	if end <= 0 || end+1 < len(text) && text[end+1] != ' ' {
+		return "", "", false
+	}
+
+	k, err := parseYAMLScalar(text[:end])
+	if err != nil || k.isObject || k.isArray {
+		return "", "", false
+	}
+
+	return k.scalar, strings.TrimLeft(text[end+1:], " "), true
+}
+
+// yamlQuotedEnd returns the index of the closing quote of the quoted scalar at the start of the "s", -1 if it is not closed.
+func yamlQuotedEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseYAMLScalar parses a scalar or a flow sequence or mapping of a single line.
+func parseYAMLScalar(s string) (*yamlValue, error) {
+	switch s[0] {
+	case '"':
+		if yamlQuotedEnd(s) != len(s)-1 {
+			return nil, fmt.Errorf("yaml: invalid double-quoted scalar %s", s)
+		}
+		var value string
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, fmt.Errorf("yaml: invalid double-quoted scalar %s: %w", s, err)
+		}
+		return &yamlValue{scalar: value, isString: true}, nil
+	case '\'':
+		if yamlQuotedEnd(s) != len(s)-1 {
+			return nil, fmt.Errorf("yaml: invalid single-quoted scalar %s", s)
+		}
+		return &yamlValue{scalar: strings.ReplaceAll(s[1:len(s)-1], "''", "'"), isString: true}, nil
+	case '[', '{':
+		return parseYAMLFlow(s)
+	case '|', '>', '&', '*', '!':
+		return nil, fmt.Errorf("yaml: unsupported value %s", s)
+	}
+
+	switch s {
+	case "null", "Null", "NULL", "~":
+		return &yamlValue{scalar: "null"}, nil
+	case "true", "True", "TRUE":
+		return &yamlValue{scalar: "true"}, nil
+	case "false", "False", "FALSE":
+		return &yamlValue{scalar: "false"}, nil
+	}
+
+	if c := s[0]; (c >= '0' && c <= '9' || c == '-') && json.Valid([]byte(s)) {
+		return &yamlValue{scalar: s}, nil
+	}
+
+	return &yamlValue{scalar: s, isString: true}, nil
+}
+
+// parseYAMLFlow parses a flow sequence, i.e "[GET, POST]", or a flow mapping, i.e "{name: users}".
+func parseYAMLFlow(s string) (*yamlValue, error) {
+	end := byte(']')
+	if s[0] == '{' {
+		end = '}'
+	}
+	if s[len(s)-1] != end {
+		return nil, fmt.Errorf("yaml: unclosed flow collection %s", s)
+	}
+
+	v := &yamlValue{isArray: end == ']', isObject: end == '}'}
+	for _, item := range splitYAMLFlow(s[1 : len(s)-1]) {
+		if item == "" {
+			continue
+		}
+
+		if v.isObject {
+			key, rest, ok := splitYAMLKey(item)
+			if !ok {
+				return nil, fmt.Errorf("yaml: invalid flow mapping %s", s)
+			}
+
+			value := &yamlValue{scalar: "null"}
+			if rest != "" {
+				var err error
+				if value, err = parseYAMLScalar(rest); err != nil {
+					return nil, err
+				}
+			}
+			v.keys = append(v.keys, key)
+			v.values = append(v.values, value)
+			continue
+		}
+
+		value, err := parseYAMLScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		v.values = append(v.values, value)
+	}
+
+	return v, nil
+}
+
+// splitYAMLFlow splits the items of a flow collection by the commas outside of quotes and nested collections.
+func splitYAMLFlow(s string) (items []string) {
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\'':
+			if end := yamlQuotedEnd(s[i:]); end != -1 {
+				i += end
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	return append(items, strings.TrimSpace(s[start:]))
+}
+
+func writeJSONValue(b *bytes.Buffer, v *yamlValue) {
+	switch {
+	case v.isObject:
+		b.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			k, _ := json.Marshal(key)
+			b.Write(k)
+			b.WriteByte(':')
+			writeJSONValue(b, v.values[i])
+		}
+		b.WriteByte('}')
+	case v.isArray:
+		b.WriteByte('[')
+		for i, item := range v.values {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeJSONValue(b, item)
+		}
+		b.WriteByte(']')
+	case v.isString:
+		s, _ := json.Marshal(v.scalar)
+		b.Write(s)
+	default:
+		b.WriteString(v.scalar)
+	}
+}